@@ -0,0 +1,185 @@
+package conf
+
+import (
+	"context"
+	"fmt"
+)
+
+// Action 是对配置键的操作
+type Action int
+
+const (
+	ActionRead Action = iota
+	ActionWrite
+	ActionDelete
+)
+
+// String 返回操作名称
+func (a Action) String() string {
+	switch a {
+	case ActionRead:
+		return "read"
+	case ActionWrite:
+		return "write"
+	case ActionDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// Principal 是发起操作的主体
+type Principal struct {
+	ID    string
+	Roles []string
+}
+
+type principalKey struct{}
+
+// WithPrincipal 返回携带主体的 context
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext 返回 context 中携带的主体
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// Authorizer 决定主体能否对键执行操作，拒绝时返回包装了 ErrPermissionDenied 的错误。
+// principal 总是非 nil，没有主体的请求在调用授权器之前就被拒绝。
+type Authorizer interface {
+	Authorize(ctx context.Context, principal *Principal, action Action, key string) error
+}
+
+// SetAuthorizer 设置授权器，为 nil 时不做检查。
+// 设置后管理器的所有写入路径（Set/Delete、Import、Rollback、SyncFromViper 等）都会检查权限，
+// 不带 context 的写入没有主体，因此会被拒绝，需要改用对应的 ...Context 方法。
+// 读取只有 GetContext 检查权限。
+func (sm *SettingManager) SetAuthorizer(a Authorizer) {
+	sm.authorizer = a
+}
+
+// authorize 使用 context 中的主体检查权限，没有主体时直接拒绝
+func (sm *SettingManager) authorize(ctx context.Context, action Action, key string) error {
+	if sm.authorizer == nil {
+		return nil
+	}
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return fmt.Errorf("%w: anonymous %s %s", ErrPermissionDenied, action, key)
+	}
+	return sm.authorizer.Authorize(ctx, principal, action, key)
+}
+
+// authorizeWrites 在批量写入之前检查所有键的权限，避免因中途被拒绝而只应用了一部分
+func (sm *SettingManager) authorizeWrites(ctx context.Context, writes, deletes []string) error {
+	for _, key := range writes {
+		if err := sm.authorize(ctx, ActionWrite, key); err != nil {
+			return err
+		}
+	}
+	for _, key := range deletes {
+		if err := sm.authorize(ctx, ActionDelete, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetContext 检查读取权限后返回配置
+func (sm *SettingManager) GetContext(ctx context.Context, key string) (any, error) {
+	if err := sm.authorize(ctx, ActionRead, key); err != nil {
+		return nil, err
+	}
+	return sm.Get(key)
+}
+
+// SetContext 检查写入权限后设置配置
+func (sm *SettingManager) SetContext(ctx context.Context, key string, value any) error {
+	return sm.set(ctx, key, value)
+}
+
+// DeleteContext 检查删除权限后删除配置
+func (sm *SettingManager) DeleteContext(ctx context.Context, key string) error {
+	return sm.delete(ctx, key)
+}
+
+// GetContext retrieves a setting by key after checking read permission
+func GetContext[T any](ctx context.Context, key string) (*T, error) {
+	if _settingsManager == nil {
+		return nil, fmt.Errorf("settings manager not initialized")
+	}
+	if err := _settingsManager.authorize(ctx, ActionRead, key); err != nil {
+		return nil, err
+	}
+	return getTyped[T](_settingsManager, key)
+}
+
+// SetContext stores a setting after checking write permission
+func SetContext[T any](ctx context.Context, key string, value T) error {
+	return _settingsManager.SetContext(ctx, key, value)
+}
+
+// DeleteContext removes a setting after checking delete permission
+func DeleteContext(ctx context.Context, key string) error {
+	return _settingsManager.DeleteContext(ctx, key)
+}
+
+// AccessRule 授予某个角色对匹配模式的键执行指定操作的权限
+type AccessRule struct {
+	// Role 角色名，"*" 表示任意已认证的主体
+	Role string
+	// Patterns 键模式（语法同 matchKey），例如 "app.*"、"feature.enabled"
+	Patterns []string
+	// Actions 允许的操作
+	Actions []Action
+}
+
+// RuleAuthorizer 基于规则的授权器：主体的任意角色有匹配的规则即允许，否则拒绝。
+// 没有主体的请求总是被拒绝。
+type RuleAuthorizer struct {
+	rules []AccessRule
+}
+
+// NewRuleAuthorizer 创建基于规则的授权器
+func NewRuleAuthorizer(rules ...AccessRule) *RuleAuthorizer {
+	return &RuleAuthorizer{rules: rules}
+}
+
+func (ra *RuleAuthorizer) Authorize(ctx context.Context, principal *Principal, action Action, key string) error {
+	if principal == nil {
+		return fmt.Errorf("%w: anonymous %s %s", ErrPermissionDenied, action, key)
+	}
+	for _, rule := range ra.rules {
+		if ruleAllows(rule, principal, action, key) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s cannot %s %s", ErrPermissionDenied, principal.ID, action, key)
+}
+
+func ruleAllows(rule AccessRule, principal *Principal, action Action, key string) bool {
+	if !hasRole(principal, rule.Role) || !matchAnyKey(rule.Patterns, key) {
+		return false
+	}
+	for _, a := range rule.Actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRole(principal *Principal, role string) bool {
+	if role == "*" {
+		return true
+	}
+	for _, r := range principal.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
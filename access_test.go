@@ -0,0 +1,126 @@
+package conf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuleAuthorizer(t *testing.T) {
+	manager := newSettingManager(newMockStorage())
+	manager.SetAuthorizer(NewRuleAuthorizer(
+		AccessRule{Role: "*", Patterns: []string{"*"}, Actions: []Action{ActionRead}},
+		AccessRule{Role: "payments", Patterns: []string{"payment.*"}, Actions: []Action{ActionWrite, ActionDelete}},
+		AccessRule{Role: "admin", Patterns: []string{"*"}, Actions: []Action{ActionRead, ActionWrite, ActionDelete}},
+	))
+
+	admin := WithPrincipal(context.Background(), &Principal{ID: "alice", Roles: []string{"admin"}})
+	payments := WithPrincipal(context.Background(), &Principal{ID: "bob", Roles: []string{"payments"}})
+	anonymous := context.Background()
+
+	require.NoError(t, manager.SetContext(admin, "app.name", "demo"))
+	require.NoError(t, manager.SetContext(payments, "payment.enabled", true))
+
+	err := manager.SetContext(payments, "app.name", "other")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Contains(t, err.Error(), "bob cannot write app.name")
+	assert.ErrorIs(t, manager.DeleteContext(payments, "app.name"), ErrPermissionDenied)
+
+	value, err := manager.GetContext(payments, "app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "demo", value)
+
+	_, err = manager.GetContext(anonymous, "app.name")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	require.NoError(t, manager.DeleteContext(payments, "payment.enabled"))
+	_, err = manager.Get("payment.enabled")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 不带 context 的写入没有主体，会被拒绝
+	assert.ErrorIs(t, manager.Set("app.name", "internal"), ErrPermissionDenied)
+	assert.ErrorIs(t, manager.Delete("app.name"), ErrPermissionDenied)
+}
+
+// denyAll 拒绝所有请求并记录调用时的主体
+type denyAll struct {
+	principals []*Principal
+}
+
+func (d *denyAll) Authorize(ctx context.Context, principal *Principal, action Action, key string) error {
+	d.principals = append(d.principals, principal)
+	return fmt.Errorf("%w: %s", ErrPermissionDenied, key)
+}
+
+func TestAuthorizer_MissingPrincipal(t *testing.T) {
+	manager := newSettingManager(newMockStorage())
+	authorizer := &denyAll{}
+	manager.SetAuthorizer(authorizer)
+
+	assert.ErrorIs(t, manager.SetContext(context.Background(), "app.name", "demo"), ErrPermissionDenied)
+	_, err := manager.GetContext(context.Background(), "app.name")
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	// 没有主体的请求不会交给授权器
+	assert.Empty(t, authorizer.principals)
+}
+
+func TestAuthorizer_Import(t *testing.T) {
+	storage := newMockStorage()
+	manager := newSettingManager(storage)
+	require.NoError(t, manager.Set("app.name", "demo"))
+	manager.SetAuthorizer(NewRuleAuthorizer(
+		AccessRule{Role: "payments", Patterns: []string{"payment.*"}, Actions: []Action{ActionWrite, ActionDelete}},
+	))
+	payments := WithPrincipal(context.Background(), &Principal{ID: "bob", Roles: []string{"payments"}})
+	input := "payment.enabled=true\napp.name=other\n"
+
+	_, err := manager.Import(strings.NewReader(input), FormatDotenv, ImportMerge)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	// 任何一个键被拒绝时都不会写入
+	_, err = manager.ImportContext(payments, strings.NewReader(input), FormatDotenv, ImportMerge)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+	assert.Equal(t, map[string]string{"app.name": "demo"}, storage.data)
+
+	result, err := manager.ImportContext(payments, strings.NewReader("payment.enabled=true\n"), FormatDotenv, ImportMerge)
+	require.NoError(t, err)
+	assert.Len(t, result.Added, 1)
+}
+
+func TestAuthorizer_Rollback(t *testing.T) {
+	manager, backend := newTestHistoryManager(t)
+	require.NoError(t, manager.Set("app.name", "v1"))
+	start := pause()
+	require.NoError(t, manager.Set("app.name", "v2"))
+	require.NoError(t, manager.Set("app.port", "8080"))
+
+	revisions, err := manager.History("app.name")
+	require.NoError(t, err)
+	first := revisions[len(revisions)-1].ID
+
+	manager.SetAuthorizer(NewRuleAuthorizer(
+		AccessRule{Role: "ops", Patterns: []string{"app.name"}, Actions: []Action{ActionWrite}},
+	))
+	ops := WithPrincipal(context.Background(), &Principal{ID: "carol", Roles: []string{"ops"}})
+
+	assert.ErrorIs(t, manager.Rollback("app.name", first), ErrPermissionDenied)
+	// app.port 需要删除权限，整个回滚被拒绝
+	assert.ErrorIs(t, manager.RollbackAllContext(ops, start), ErrPermissionDenied)
+	assert.Equal(t, "v2", backend.data["app.name"])
+	assert.Equal(t, "8080", backend.data["app.port"])
+
+	require.NoError(t, manager.RollbackContext(ops, "app.name", first))
+	assert.Equal(t, "v1", backend.data["app.name"])
+}
+
+func TestSettingManager_NoAuthorizer(t *testing.T) {
+	manager := newSettingManager(newMockStorage())
+	require.NoError(t, manager.SetContext(context.Background(), "app.name", "demo"))
+	value, err := manager.GetContext(context.Background(), "app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "demo", value)
+}
@@ -0,0 +1,216 @@
+package conf
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AuditAction 是被审计的操作
+type AuditAction string
+
+const (
+	AuditSet    AuditAction = "set"
+	AuditDelete AuditAction = "delete"
+)
+
+// AuditEntry 记录一次通过 SettingManager 进行的修改，敏感键的值会被隐藏。
+// OldExists 区分修改前键不存在和值为空字符串两种情况。
+type AuditEntry struct {
+	ID        uint        `gorm:"primaryKey" json:"-"`
+	Key       string      `gorm:"index;not null" json:"key"`
+	Action    AuditAction `gorm:"not null" json:"action"`
+	OldValue  string      `json:"old_value,omitempty"`
+	OldExists bool        `json:"old_exists"`
+	NewValue  string      `json:"new_value,omitempty"`
+	Actor     string      `json:"actor,omitempty"`
+	Reason    string      `json:"reason,omitempty"`
+	Time      time.Time   `gorm:"index;not null" json:"time"`
+}
+
+// TableName 指定审计记录的表名
+func (AuditEntry) TableName() string {
+	return "setting_audit"
+}
+
+// AuditSink 保存审计记录
+type AuditSink interface {
+	Record(entry AuditEntry) error
+}
+
+// AuditQuerier 由支持查询的审计存储实现，返回键的审计记录，最新的在前
+type AuditQuerier interface {
+	AuditHistory(key string) ([]AuditEntry, error)
+}
+
+type reasonKey struct{}
+
+// WithReason 返回携带修改原因的 context，SetContext/DeleteContext 会将其写入审计记录
+func WithReason(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, reasonKey{}, reason)
+}
+
+// SetAuditSink 设置审计记录的存储，为 nil 时关闭审计。
+// 每次 Set/Delete 都会在写入存储层之前记录，记录失败时放弃本次修改并返回 ErrAudit，
+// 因此不会出现没有审计记录的修改；存储层写入失败时审计记录已经存在，调用方会收到存储层的错误。
+// 操作者取自 context 中的主体。
+func (sm *SettingManager) SetAuditSink(sink AuditSink) {
+	sm.auditSink = sink
+}
+
+// AuditHistory 返回键的审计记录，最新的在前
+func (sm *SettingManager) AuditHistory(key string) ([]AuditEntry, error) {
+	querier, ok := sm.auditSink.(AuditQuerier)
+	if !ok {
+		return nil, fmt.Errorf("%w: audit sink does not support queries", ErrAudit)
+	}
+	return querier.AuditHistory(key)
+}
+
+// audit 在修改之前记录审计，失败时返回包装了 ErrAudit 的错误，调用方不应继续修改
+func (sm *SettingManager) audit(ctx context.Context, action AuditAction, key, newValue string) error {
+	if sm.auditSink == nil {
+		return nil
+	}
+
+	oldValue, err := sm.storage.Get(key)
+	oldExists := err == nil
+	if err != nil && !errors.Is(err, ErrKeyNotFound) {
+		return fmt.Errorf("%w: read %s: %w", ErrAudit, key, err)
+	}
+
+	entry := AuditEntry{
+		Key:       key,
+		Action:    action,
+		OldValue:  sm.redactString(key, oldValue),
+		OldExists: oldExists,
+		NewValue:  sm.redactString(key, newValue),
+		Time:      time.Now(),
+	}
+	if !oldExists {
+		entry.OldValue = ""
+	}
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		entry.Actor = principal.ID
+	}
+	entry.Reason, _ = ctx.Value(reasonKey{}).(string)
+
+	if err := sm.auditSink.Record(entry); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrAudit, key, err)
+	}
+	return nil
+}
+
+// GormAuditSink 将审计记录保存到数据库的 setting_audit 表
+type GormAuditSink struct {
+	db *gorm.DB
+}
+
+// NewGormAuditSink 创建数据库审计存储，并自动创建 setting_audit 表
+func NewGormAuditSink(db *gorm.DB) (*GormAuditSink, error) {
+	if err := db.AutoMigrate(&AuditEntry{}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAudit, err)
+	}
+	return &GormAuditSink{db: db}, nil
+}
+
+func (gs *GormAuditSink) Record(entry AuditEntry) error {
+	return gs.db.Create(&entry).Error
+}
+
+// AuditHistory 返回键的审计记录，最新的在前
+func (gs *GormAuditSink) AuditHistory(key string) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	if err := gs.db.Where(&AuditEntry{Key: key}).Order("id desc").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAudit, err)
+	}
+	return entries, nil
+}
+
+// JSONLAuditSink 将审计记录以 JSON Lines 格式追加到文件
+type JSONLAuditSink struct {
+	path  string
+	mutex sync.Mutex
+	file  *os.File
+}
+
+// NewJSONLAuditSink 以追加方式打开审计文件，使用完毕后应调用 Close
+func NewJSONLAuditSink(path string) (*JSONLAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAudit, err)
+	}
+	return &JSONLAuditSink{path: path, file: file}, nil
+}
+
+func (js *JSONLAuditSink) Record(entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	if js.file == nil {
+		return os.ErrClosed
+	}
+	_, err = js.file.Write(append(data, '\n'))
+	return err
+}
+
+// AuditHistory 扫描审计文件，返回键的审计记录，最新的在前
+func (js *JSONLAuditSink) AuditHistory(key string) ([]AuditEntry, error) {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	file, err := os.Open(js.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAudit, err)
+	}
+	defer file.Close()
+
+	var entries []AuditEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxSettingBodySize*4)
+	for scanner.Scan() {
+		var entry AuditEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrAudit, js.path, err)
+		}
+		if entry.Key == key {
+			entries = append(entries, entry)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrAudit, err)
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
+
+// Close 关闭审计文件
+func (js *JSONLAuditSink) Close() error {
+	js.mutex.Lock()
+	defer js.mutex.Unlock()
+
+	if js.file == nil {
+		return nil
+	}
+	err := js.file.Close()
+	js.file = nil
+	return err
+}
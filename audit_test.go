@@ -0,0 +1,104 @@
+package conf
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func testAuditSinks(t *testing.T) map[string]AuditSink {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "audit.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	require.NoError(t, err)
+	gormSink, err := NewGormAuditSink(db)
+	require.NoError(t, err)
+
+	jsonlSink, err := NewJSONLAuditSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = jsonlSink.Close() })
+
+	return map[string]AuditSink{"gorm": gormSink, "jsonl": jsonlSink}
+}
+
+func TestAudit_Sinks(t *testing.T) {
+	for name, sink := range testAuditSinks(t) {
+		t.Run(name, func(t *testing.T) {
+			manager := newSettingManager(newMockStorage())
+			manager.SetAuditSink(sink)
+			manager.MarkSensitive("*.password")
+
+			ctx := WithPrincipal(context.Background(), &Principal{ID: "alice"})
+			ctx = WithReason(ctx, "incident 42")
+
+			require.NoError(t, manager.Set("payment.enabled", true))
+			require.NoError(t, manager.SetContext(ctx, "payment.enabled", false))
+			require.NoError(t, manager.DeleteContext(ctx, "payment.enabled"))
+			require.NoError(t, manager.Set("db.password", "hunter2"))
+
+			history, err := manager.AuditHistory("payment.enabled")
+			require.NoError(t, err)
+			require.Len(t, history, 3)
+
+			assert.Equal(t, AuditDelete, history[0].Action)
+			assert.Equal(t, "false", history[0].OldValue)
+			assert.True(t, history[0].OldExists)
+			assert.Equal(t, "alice", history[0].Actor)
+
+			assert.Equal(t, AuditSet, history[1].Action)
+			assert.Equal(t, "true", history[1].OldValue)
+			assert.Equal(t, "false", history[1].NewValue)
+			assert.Equal(t, "incident 42", history[1].Reason)
+			assert.False(t, history[1].Time.IsZero())
+
+			assert.Empty(t, history[2].OldValue)
+			assert.False(t, history[2].OldExists)
+			assert.Empty(t, history[2].Actor)
+
+			// 旧值为空字符串与键不存在可以区分
+			require.NoError(t, manager.Set("app.banner", ""))
+			require.NoError(t, manager.Set("app.banner", "hello"))
+			history, err = manager.AuditHistory("app.banner")
+			require.NoError(t, err)
+			require.Len(t, history, 2)
+			assert.True(t, history[0].OldExists)
+			assert.Empty(t, history[0].OldValue)
+			assert.False(t, history[1].OldExists)
+
+			history, err = manager.AuditHistory("db.password")
+			require.NoError(t, err)
+			require.Len(t, history, 1)
+			assert.Equal(t, RedactedValue, history[0].NewValue)
+		})
+	}
+}
+
+func TestAudit_SinkFailure(t *testing.T) {
+	sink, err := NewJSONLAuditSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+
+	storage := newMockStorage()
+	storage.data["app.port"] = "8080"
+	manager := newSettingManager(storage)
+	manager.SetAuditSink(sink)
+
+	// 审计记录失败时修改不会生效，调用方可以安全地重试
+	assert.ErrorIs(t, manager.Set("app.name", "demo"), ErrAudit)
+	assert.ErrorIs(t, manager.Delete("app.port"), ErrAudit)
+	_, err = manager.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.Equal(t, "8080", storage.data["app.port"])
+
+	manager.SetAuditSink(nil)
+	_, err = manager.AuditHistory("app.name")
+	assert.ErrorIs(t, err, ErrAudit)
+}
@@ -0,0 +1,129 @@
+package conf
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// 默认使用的 bucket 名称
+const boltDefaultBucket = "settings"
+
+// BoltStorage 基于纯 Go 的嵌入式 KV 引擎 bbolt 的存储，无需 cgo，适合边缘节点
+type BoltStorage struct {
+	db     *bolt.DB
+	bucket []byte
+}
+
+// NewBoltStorage 打开（或创建）bbolt 数据库文件，使用完毕后应调用 Close
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("%w: open %s: %v", ErrStorageOperation, path, err)
+	}
+
+	bs := &BoltStorage{db: db, bucket: []byte(boltDefaultBucket)}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bs.bucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("%w: create bucket: %v", ErrStorageOperation, err)
+	}
+	return bs, nil
+}
+
+func (bs *BoltStorage) Get(key string) (string, error) {
+	var value string
+	err := bs.View(func(tx SettingTx) error {
+		var err error
+		value, err = tx.Get(key)
+		return err
+	})
+	return value, err
+}
+
+func (bs *BoltStorage) Set(key, value string) error {
+	return bs.Update(func(tx SettingTx) error {
+		return tx.Set(key, value)
+	})
+}
+
+func (bs *BoltStorage) Delete(key string) error {
+	return bs.Update(func(tx SettingTx) error {
+		return tx.Delete(key)
+	})
+}
+
+// Keys 返回以 prefix 开头的所有键
+func (bs *BoltStorage) Keys(prefix string) ([]string, error) {
+	var keys []string
+	err := bs.Scan(prefix, func(key, _ string) error {
+		keys = append(keys, key)
+		return nil
+	})
+	return keys, err
+}
+
+// Scan 按字典序遍历以 prefix 开头的键值，fn 返回错误时停止遍历
+func (bs *BoltStorage) Scan(prefix string, fn func(key, value string) error) error {
+	return bs.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bs.bucket).Cursor()
+		p := []byte(prefix)
+		for k, v := c.Seek(p); k != nil && bytes.HasPrefix(k, p); k, v = c.Next() {
+			if err := fn(string(k), string(v)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Update 在读写事务中执行 fn，fn 返回错误时回滚所有修改
+func (bs *BoltStorage) Update(fn func(tx SettingTx) error) error {
+	return bs.db.Update(func(tx *bolt.Tx) error {
+		return fn(&boltTx{bucket: tx.Bucket(bs.bucket)})
+	})
+}
+
+// View 在只读事务中执行 fn
+func (bs *BoltStorage) View(fn func(tx SettingTx) error) error {
+	return bs.db.View(func(tx *bolt.Tx) error {
+		return fn(&boltTx{bucket: tx.Bucket(bs.bucket)})
+	})
+}
+
+// Close 关闭数据库文件
+func (bs *BoltStorage) Close() error {
+	return bs.db.Close()
+}
+
+type boltTx struct {
+	bucket *bolt.Bucket
+}
+
+func (t *boltTx) Get(key string) (string, error) {
+	value := t.bucket.Get([]byte(key))
+	if value == nil {
+		return "", ErrKeyNotFound
+	}
+	// bbolt 返回的切片只在事务内有效，转换为字符串时会复制
+	return string(value), nil
+}
+
+func (t *boltTx) Set(key, value string) error {
+	if err := t.bucket.Put([]byte(key), []byte(value)); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+func (t *boltTx) Delete(key string) error {
+	if err := t.bucket.Delete([]byte(key)); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package conf
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBoltStorage(t *testing.T) *BoltStorage {
+	t.Helper()
+
+	storage, err := NewBoltStorage(filepath.Join(t.TempDir(), "settings.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = storage.Close() })
+	return storage
+}
+
+func TestBoltStorage_BasicOperations(t *testing.T) {
+	storage := newTestBoltStorage(t)
+
+	_, err := storage.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.NoError(t, storage.Set("app.name", "demo"))
+	require.NoError(t, storage.Set("app.empty", ""))
+
+	value, err := storage.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "demo", value)
+
+	empty, err := storage.Get("app.empty")
+	require.NoError(t, err)
+	assert.Equal(t, "", empty)
+
+	require.NoError(t, storage.Delete("app.name"))
+	_, err = storage.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 通过管理器使用
+	manager := newSettingManager(storage)
+	require.NoError(t, manager.Set("app.port", 8080))
+	port, err := getTyped[int](newSettingManager(storage), "app.port")
+	require.NoError(t, err)
+	assert.Equal(t, 8080, *port)
+}
+
+func TestBoltStorage_Scan(t *testing.T) {
+	storage := newTestBoltStorage(t)
+	for _, key := range []string{"app.db.host", "app.db.port", "app.name", "other"} {
+		require.NoError(t, storage.Set(key, key+"-value"))
+	}
+
+	keys, err := storage.Keys("app.db.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.db.host", "app.db.port"}, keys)
+
+	values := make(map[string]string)
+	require.NoError(t, storage.Scan("app.", func(key, value string) error {
+		values[key] = value
+		return nil
+	}))
+	assert.Len(t, values, 3)
+	assert.Equal(t, "app.name-value", values["app.name"])
+}
+
+func TestBoltStorage_Transactions(t *testing.T) {
+	storage := newTestBoltStorage(t)
+	require.NoError(t, storage.Set("balance.a", "100"))
+
+	errAbort := errors.New("abort")
+	err := storage.Update(func(tx SettingTx) error {
+		require.NoError(t, tx.Set("balance.a", "50"))
+		require.NoError(t, tx.Set("balance.b", "50"))
+		return errAbort
+	})
+	assert.ErrorIs(t, err, errAbort)
+
+	// 事务回滚后数据保持不变
+	value, err := storage.Get("balance.a")
+	require.NoError(t, err)
+	assert.Equal(t, "100", value)
+	_, err = storage.Get("balance.b")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.NoError(t, storage.Update(func(tx SettingTx) error {
+		if err := tx.Set("balance.a", "50"); err != nil {
+			return err
+		}
+		return tx.Set("balance.b", "50")
+	}))
+	value, err = storage.Get("balance.b")
+	require.NoError(t, err)
+	assert.Equal(t, "50", value)
+
+	var _ SettingTransactor = storage
+	var _ SettingLister = storage
+}
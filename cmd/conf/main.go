@@ -0,0 +1,587 @@
+// conf 是管理配置存储的命令行工具，支持 SQLite 文件以及 conf 包提供的其他存储。
+//
+// 用法：
+//
+//	conf [-store DSN] [-json] <command> [flags] [args]
+//
+// 存储通过 -store 或环境变量 CONF_STORE 指定，默认为 sqlite:config.db，支持：
+//
+//	sqlite:PATH          SQLite 数据库，记录修订历史
+//	file:PATH            JSON/YAML/TOML/.env 文件，格式由扩展名决定
+//	dir:DIR              目录存储，每个键一个文件
+//	bolt:PATH            BoltDB 文件
+//	git:DIR              Git 仓库
+//	env:PREFIX           环境变量（只读）
+//	http(s)://HOST       HTTP 配置服务
+//
+// 省略前缀时按扩展名推断：.db/.sqlite 为 SQLite，.json/.yaml/.toml/.env 为文件存储。
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hope183/conf"
+	"github.com/hope183/conf/sqlite"
+)
+
+const usage = `usage: conf [-store DSN] [-json] <command> [flags] [args]
+
+commands:
+  get [-type T] KEY            print a setting
+  set [-type T] KEY VALUE      write a setting
+  delete KEY                   delete a setting
+  list [PREFIX]                list keys
+  export [-format F] [-o FILE] export all settings
+  import [-format F] [-policy P] FILE|-
+                               import settings (policy: merge, replace, dry-run)
+  diff [-prefix P] OTHER_DSN   compare with another store
+  history KEY                  show revisions of a setting
+
+types: string, int, float, bool, duration, json
+`
+
+// errUsage 表示命令行参数错误，退出码为 2
+var errUsage = errors.New("invalid usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// cli 保存一次运行的全局选项和输出
+type cli struct {
+	dsn     string
+	storage conf.SettingStorage
+	manager *conf.SettingManager
+	closers []func() error
+	json    bool
+	stdin   io.Reader
+	stdout  io.Writer
+}
+
+// run 执行命令并返回退出码
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	global := flag.NewFlagSet("conf", flag.ContinueOnError)
+	global.SetOutput(stderr)
+	global.Usage = func() { fmt.Fprint(stderr, usage) }
+	dsn := global.String("store", defaultStore(), "storage DSN")
+	jsonOutput := global.Bool("json", false, "print JSON output")
+	if err := global.Parse(args); err != nil {
+		return 2
+	}
+	if global.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	c := &cli{
+		dsn:    *dsn,
+		json:   *jsonOutput,
+		stdin:  stdin,
+		stdout: stdout,
+	}
+	defer c.close()
+
+	err := c.dispatch(global.Arg(0), global.Args()[1:], stderr)
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "conf: %v\n", err)
+		return 2
+	default:
+		fmt.Fprintf(stderr, "conf: %v\n", err)
+		return 1
+	}
+}
+
+func defaultStore() string {
+	if dsn := os.Getenv("CONF_STORE"); dsn != "" {
+		return dsn
+	}
+	return "sqlite:config.db"
+}
+
+// open 在参数解析通过之后打开存储，create 为 false 时存储必须已经存在
+func (c *cli) open(create bool) error {
+	storage, closeStore, err := openStore(c.dsn, create)
+	if err != nil {
+		return err
+	}
+	c.closers = append(c.closers, closeStore)
+	c.storage = storage
+	c.manager = conf.NewManager(storage)
+	return nil
+}
+
+func (c *cli) close() {
+	for _, closeStore := range c.closers {
+		_ = closeStore()
+	}
+}
+
+func (c *cli) dispatch(command string, args []string, stderr io.Writer) error {
+	commands := map[string]func(*flag.FlagSet, []string) error{
+		"get":     c.get,
+		"set":     c.set,
+		"delete":  c.delete,
+		"list":    c.list,
+		"export":  c.export,
+		"import":  c.importSettings,
+		"diff":    c.diff,
+		"history": c.history,
+	}
+	cmd, ok := commands[command]
+	if !ok {
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	return cmd(flags, args)
+}
+
+// parseArgs 解析子命令参数，并检查位置参数的个数在 [min, max] 之间
+func parseArgs(flags *flag.FlagSet, args []string, min, max int) error {
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if n := flags.NArg(); n < min || n > max {
+		return fmt.Errorf("%w: %s expects %s", errUsage, flags.Name(), argCount(min, max))
+	}
+	return nil
+}
+
+func argCount(min, max int) string {
+	if min == max {
+		return fmt.Sprintf("%d argument(s)", min)
+	}
+	return fmt.Sprintf("%d to %d arguments", min, max)
+}
+
+func (c *cli) get(flags *flag.FlagSet, args []string) error {
+	typ := flags.String("type", "string", "value type")
+	if err := parseArgs(flags, args, 1, 1); err != nil {
+		return err
+	}
+	key := flags.Arg(0)
+	if err := c.open(false); err != nil {
+		return err
+	}
+
+	value, err := getTyped(c.manager, key, *typ)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", key, err)
+	}
+	if c.json {
+		return c.writeJSON(map[string]any{"key": key, "value": value})
+	}
+	switch v := value.(type) {
+	case json.RawMessage:
+		fmt.Fprintln(c.stdout, string(v))
+	default:
+		fmt.Fprintln(c.stdout, v)
+	}
+	return nil
+}
+
+// getTyped 按类型从管理器读取配置
+func getTyped(manager *conf.SettingManager, key, typ string) (any, error) {
+	switch typ {
+	case "string":
+		return deref(conf.GetFrom[string](manager, key))
+	case "int":
+		return deref(conf.GetFrom[int64](manager, key))
+	case "float":
+		return deref(conf.GetFrom[float64](manager, key))
+	case "bool":
+		return deref(conf.GetFrom[bool](manager, key))
+	case "duration":
+		d, err := deref(conf.GetFrom[time.Duration](manager, key))
+		if err != nil {
+			return nil, err
+		}
+		return d.String(), nil
+	case "json":
+		s, err := deref(conf.GetFrom[string](manager, key))
+		if err != nil {
+			return nil, err
+		}
+		if !json.Valid([]byte(s)) {
+			return nil, fmt.Errorf("%w: value is not valid JSON", conf.ErrTypeConversion)
+		}
+		return json.RawMessage(s), nil
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", errUsage, typ)
+	}
+}
+
+func deref[T any](value *T, err error) (T, error) {
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return *value, nil
+}
+
+func (c *cli) set(flags *flag.FlagSet, args []string) error {
+	typ := flags.String("type", "string", "value type")
+	if err := parseArgs(flags, args, 2, 2); err != nil {
+		return err
+	}
+	key, raw := flags.Arg(0), flags.Arg(1)
+
+	value, err := parseTyped(raw, *typ)
+	if err != nil {
+		return err
+	}
+	if err := c.open(true); err != nil {
+		return err
+	}
+	if err := c.manager.Set(key, value); err != nil {
+		return fmt.Errorf("set %s: %w", key, err)
+	}
+	return nil
+}
+
+// parseTyped 按类型校验并转换命令行输入的值
+func parseTyped(raw, typ string) (any, error) {
+	var (
+		value any
+		err   error
+	)
+	switch typ {
+	case "string":
+		return raw, nil
+	case "int":
+		value, err = strconv.ParseInt(raw, 10, 64)
+	case "float":
+		value, err = strconv.ParseFloat(raw, 64)
+	case "bool":
+		value, err = strconv.ParseBool(raw)
+	case "duration":
+		var d time.Duration
+		d, err = time.ParseDuration(raw)
+		value = d.String()
+	case "json":
+		if !json.Valid([]byte(raw)) {
+			err = errors.New("invalid JSON")
+		}
+		value = raw
+	default:
+		return nil, fmt.Errorf("%w: unknown type %q", errUsage, typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q is not a valid %s: %v", conf.ErrTypeConversion, raw, typ, err)
+	}
+	return value, nil
+}
+
+func (c *cli) delete(flags *flag.FlagSet, args []string) error {
+	if err := parseArgs(flags, args, 1, 1); err != nil {
+		return err
+	}
+	key := flags.Arg(0)
+	if err := c.open(true); err != nil {
+		return err
+	}
+	if err := c.manager.Delete(key); err != nil {
+		return fmt.Errorf("delete %s: %w", key, err)
+	}
+	return nil
+}
+
+func (c *cli) list(flags *flag.FlagSet, args []string) error {
+	if err := parseArgs(flags, args, 0, 1); err != nil {
+		return err
+	}
+	if err := c.open(false); err != nil {
+		return err
+	}
+	lister, ok := c.storage.(conf.SettingLister)
+	if !ok {
+		return fmt.Errorf("list: %w: storage does not support listing", conf.ErrStorageOperation)
+	}
+	keys, err := lister.Keys(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("list: %w", err)
+	}
+	if c.json {
+		if keys == nil {
+			keys = []string{}
+		}
+		return c.writeJSON(map[string]any{"keys": keys})
+	}
+	for _, key := range keys {
+		fmt.Fprintln(c.stdout, key)
+	}
+	return nil
+}
+
+func (c *cli) export(flags *flag.FlagSet, args []string) error {
+	format := flags.String("format", "", "output format: json, yaml, toml, env (default from -o extension, or json)")
+	output := flags.String("o", "", "output file (default stdout)")
+	if err := parseArgs(flags, args, 0, 0); err != nil {
+		return err
+	}
+	f, err := resolveFormat(*format, *output)
+	if err != nil {
+		return err
+	}
+	if err := c.open(false); err != nil {
+		return err
+	}
+
+	if *output == "" {
+		return c.manager.Export(c.stdout, f)
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+	if err := c.manager.Export(file, f); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (c *cli) importSettings(flags *flag.FlagSet, args []string) error {
+	format := flags.String("format", "", "input format: json, yaml, toml, env (default from file extension, or json)")
+	policyName := flags.String("policy", "merge", "merge, replace or dry-run")
+	if err := parseArgs(flags, args, 1, 1); err != nil {
+		return err
+	}
+	policy, err := parsePolicy(*policyName)
+	if err != nil {
+		return err
+	}
+	path := flags.Arg(0)
+	if path == "-" {
+		path = ""
+	}
+	f, err := resolveFormat(*format, path)
+	if err != nil {
+		return err
+	}
+	if err := c.open(policy != conf.ImportDryRun); err != nil {
+		return err
+	}
+
+	input := c.stdin
+	if path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	result, err := c.manager.Import(input, f, policy)
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.writeJSON(result)
+	}
+	for _, change := range result.Added {
+		fmt.Fprintf(c.stdout, "+ %s = %s\n", change.Key, change.NewValue)
+	}
+	for _, change := range result.Changed {
+		fmt.Fprintf(c.stdout, "~ %s = %s (was %s)\n", change.Key, change.NewValue, change.OldValue)
+	}
+	for _, key := range result.Removed {
+		fmt.Fprintf(c.stdout, "- %s\n", key)
+	}
+	for _, key := range result.Skipped {
+		fmt.Fprintf(c.stdout, "! %s = %s (no existing value, skipped)\n", key, conf.RedactedValue)
+	}
+	if !result.Applied {
+		fmt.Fprintln(c.stdout, "dry run: no changes applied")
+	}
+	return nil
+}
+
+func parsePolicy(name string) (conf.ImportPolicy, error) {
+	for _, p := range []conf.ImportPolicy{conf.ImportMerge, conf.ImportReplace, conf.ImportDryRun} {
+		if p.String() == name {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown import policy %q", errUsage, name)
+}
+
+// resolveFormat 优先使用显式指定的格式，其次按文件扩展名推断，默认为 JSON
+func resolveFormat(name, path string) (conf.Format, error) {
+	if name == "" && path != "" && filepath.Ext(path) != "" {
+		name = filepath.Ext(path)
+	}
+	if name == "" {
+		return conf.FormatJSON, nil
+	}
+	f, err := conf.ParseFormat(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", errUsage, err)
+	}
+	return f, nil
+}
+
+func (c *cli) diff(flags *flag.FlagSet, args []string) error {
+	prefix := flags.String("prefix", "", "only compare keys with this prefix")
+	if err := parseArgs(flags, args, 1, 1); err != nil {
+		return err
+	}
+	otherDSN := flags.Arg(0)
+	if err := c.open(false); err != nil {
+		return err
+	}
+	other, closeOther, err := openStore(otherDSN, false)
+	if err != nil {
+		return err
+	}
+	defer closeOther()
+
+	result, err := c.manager.Diff(other, *prefix)
+	if err != nil {
+		return fmt.Errorf("diff: %w", err)
+	}
+	if c.json {
+		return c.writeJSON(result)
+	}
+	if result.Empty() {
+		return nil
+	}
+	return result.WriteUnified(c.stdout, "current", otherDSN)
+}
+
+func (c *cli) history(flags *flag.FlagSet, args []string) error {
+	if err := parseArgs(flags, args, 1, 1); err != nil {
+		return err
+	}
+	key := flags.Arg(0)
+	if err := c.open(false); err != nil {
+		return err
+	}
+	revisions, err := c.manager.History(key)
+	if err != nil {
+		return fmt.Errorf("history %s: %w", key, err)
+	}
+	if c.json {
+		if revisions == nil {
+			revisions = []conf.Revision{}
+		}
+		return c.writeJSON(revisions)
+	}
+	for _, rev := range revisions {
+		value := rev.Value
+		if rev.Deleted {
+			value = "(deleted)"
+		}
+		fmt.Fprintf(c.stdout, "%d\t%s\t%s\n", rev.ID, rev.Time.Local().Format(time.RFC3339), value)
+	}
+	return nil
+}
+
+func (c *cli) writeJSON(value any) error {
+	encoder := json.NewEncoder(c.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// openStore 按 DSN 打开存储，返回的 close 函数释放存储持有的资源。
+// create 为 false 时（只读命令和 diff 的对比目标）基于路径的存储必须已经存在，
+// 避免拼错的路径被静默创建为空存储。
+func openStore(dsn string, create bool) (conf.SettingStorage, func() error, error) {
+	noop := func() error { return nil }
+	scheme, target, ok := strings.Cut(dsn, ":")
+	if !ok || len(scheme) == 1 {
+		// 没有前缀（或是 Windows 盘符）时按扩展名推断
+		scheme, target = inferScheme(dsn), dsn
+	}
+	if target == "" && scheme != "env" {
+		return nil, nil, fmt.Errorf("%w: empty store path in %q", errUsage, dsn)
+	}
+	switch scheme {
+	case "sqlite", "file", "dir", "bolt", "git":
+		if _, err := os.Stat(target); errors.Is(err, os.ErrNotExist) && !create {
+			return nil, nil, fmt.Errorf("store %s does not exist", dsn)
+		}
+	}
+
+	switch scheme {
+	case "sqlite":
+		storage, err := sqlite.NewStorage(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		history, err := conf.NewHistoryStorage(storage, storage.DB())
+		if err != nil {
+			return nil, nil, err
+		}
+		closeDB := func() error {
+			db, err := storage.DB().DB()
+			if err != nil {
+				return err
+			}
+			return db.Close()
+		}
+		return history, closeDB, nil
+	case "file":
+		format, err := conf.ParseFormat(filepath.Ext(target))
+		if err != nil {
+			return nil, nil, err
+		}
+		storage, err := conf.NewFileStorage(target, format)
+		if err != nil {
+			return nil, nil, err
+		}
+		return storage, noop, nil
+	case "dir":
+		storage, err := conf.NewDirStorage(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		return storage, storage.Close, nil
+	case "bolt":
+		storage, err := conf.NewBoltStorage(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		return storage, storage.Close, nil
+	case "git":
+		storage, err := conf.NewGitStorage(target, conf.GitOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return storage, noop, nil
+	case "env":
+		storage, err := conf.NewEnvStorage(conf.EnvOptions{Prefix: target})
+		if err != nil {
+			return nil, nil, err
+		}
+		return storage, noop, nil
+	case "http", "https":
+		return conf.NewHTTPStorage(dsn, nil), noop, nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported store %q", errUsage, dsn)
+	}
+}
+
+func inferScheme(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return "sqlite"
+	case ".json", ".yaml", ".yml", ".toml", ".env":
+		return "file"
+	default:
+		return ""
+	}
+}
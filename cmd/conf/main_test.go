@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runCLI 执行命令并返回退出码、标准输出和标准错误
+func runCLI(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun_GetSetDelete(t *testing.T) {
+	store := "sqlite:" + filepath.Join(t.TempDir(), "config.db")
+
+	code, _, stderr := runCLI(t, "", "-store", store, "set", "-type", "int", "app.port", "8080")
+	require.Equal(t, 0, code, stderr)
+
+	code, stdout, _ := runCLI(t, "", "-store", store, "get", "-type", "int", "app.port")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "8080\n", stdout)
+
+	code, stdout, _ = runCLI(t, "", "-store", store, "-json", "get", "-type", "int", "app.port")
+	require.Equal(t, 0, code)
+	assert.JSONEq(t, `{"key":"app.port","value":8080}`, stdout)
+
+	code, _, stderr = runCLI(t, "", "-store", store, "set", "-type", "int", "app.port", "eighty")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "not a valid int")
+
+	code, _, _ = runCLI(t, "", "-store", store, "delete", "app.port")
+	require.Equal(t, 0, code)
+
+	code, _, stderr = runCLI(t, "", "-store", store, "get", "app.port")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "key not found")
+}
+
+func TestRun_TypedValues(t *testing.T) {
+	store := "sqlite:" + filepath.Join(t.TempDir(), "config.db")
+
+	require.Equal(t, 0, run([]string{"-store", store, "set", "-type", "duration", "app.timeout", "90s"}, nil, &bytes.Buffer{}, &bytes.Buffer{}))
+	require.Equal(t, 0, run([]string{"-store", store, "set", "-type", "json", "app.hosts", `["a","b"]`}, nil, &bytes.Buffer{}, &bytes.Buffer{}))
+	require.Equal(t, 0, run([]string{"-store", store, "set", "-type", "bool", "app.debug", "true"}, nil, &bytes.Buffer{}, &bytes.Buffer{}))
+
+	_, stdout, _ := runCLI(t, "", "-store", store, "get", "-type", "duration", "app.timeout")
+	assert.Equal(t, "1m30s\n", stdout)
+
+	_, stdout, _ = runCLI(t, "", "-store", store, "-json", "get", "-type", "json", "app.hosts")
+	assert.JSONEq(t, `{"key":"app.hosts","value":["a","b"]}`, stdout)
+
+	_, stdout, _ = runCLI(t, "", "-store", store, "-json", "get", "-type", "bool", "app.debug")
+	assert.JSONEq(t, `{"key":"app.debug","value":true}`, stdout)
+
+	code, _, _ := runCLI(t, "", "-store", store, "set", "-type", "json", "app.hosts", `["a"`)
+	assert.Equal(t, 1, code)
+}
+
+func TestRun_ListExportImport(t *testing.T) {
+	dir := t.TempDir()
+	store := "sqlite:" + filepath.Join(dir, "config.db")
+
+	input := `{"app": {"name": "demo", "port": 8080}, "db": {"host": "localhost"}}`
+	code, stdout, stderr := runCLI(t, input, "-store", store, "import", "-format", "json", "-")
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "+ app.name = demo")
+
+	_, stdout, _ = runCLI(t, "", "-store", store, "list", "app.")
+	assert.Equal(t, "app.name\napp.port\n", stdout)
+
+	_, stdout, _ = runCLI(t, "", "-store", store, "-json", "list")
+	assert.JSONEq(t, `{"keys":["app.name","app.port","db.host"]}`, stdout)
+
+	exported := filepath.Join(dir, "export.yaml")
+	code, _, stderr = runCLI(t, "", "-store", store, "export", "-o", exported)
+	require.Equal(t, 0, code, stderr)
+	data, err := os.ReadFile(exported)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "host: localhost")
+
+	code, stdout, _ = runCLI(t, `{"app": {"name": "demo"}}`, "-store", store, "-json", "import", "-policy", "dry-run", "-")
+	require.Equal(t, 0, code)
+	var result struct {
+		Removed []string `json:"removed"`
+		Applied bool     `json:"applied"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &result))
+	assert.Equal(t, []string{"app.port", "db.host"}, result.Removed)
+	assert.False(t, result.Applied)
+
+	code, _, stderr = runCLI(t, "", "-store", store, "import", "-policy", "overwrite", "-")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "unknown import policy")
+}
+
+func TestRun_DiffAndHistory(t *testing.T) {
+	dir := t.TempDir()
+	store := "sqlite:" + filepath.Join(dir, "config.db")
+	other := filepath.Join(dir, "other.json")
+	require.NoError(t, os.WriteFile(other, []byte(`{"app": {"port": "9090", "debug": "true"}}`), 0o644))
+
+	require.Equal(t, 0, run([]string{"-store", store, "set", "app.port", "8080"}, nil, &bytes.Buffer{}, &bytes.Buffer{}))
+	require.Equal(t, 0, run([]string{"-store", store, "set", "app.port", "8081"}, nil, &bytes.Buffer{}, &bytes.Buffer{}))
+
+	code, stdout, stderr := runCLI(t, "", "-store", store, "diff", other)
+	require.Equal(t, 0, code, stderr)
+	assert.Contains(t, stdout, "+app.debug = true")
+	assert.Contains(t, stdout, "-app.port = 8081\n+app.port = 9090")
+
+	code, stdout, _ = runCLI(t, "", "-store", store, "-json", "history", "app.port")
+	require.Equal(t, 0, code)
+	var revisions []struct {
+		Value string `json:"value"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &revisions))
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "8081", revisions[0].Value)
+	assert.Equal(t, "8080", revisions[1].Value)
+
+	// 文件存储不记录修订历史
+	code, _, stderr = runCLI(t, "", "-store", other, "history", "app.port")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "does not track history")
+}
+
+func TestRun_ReadOnlyRequiresExistingStore(t *testing.T) {
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.db")
+
+	for _, args := range [][]string{
+		{"get", "app.port"},
+		{"list"},
+		{"export"},
+		{"history", "app.port"},
+		{"import", "-policy", "dry-run", "-"},
+	} {
+		code, _, stderr := runCLI(t, "{}", append([]string{"-store", "sqlite:" + missing}, args...)...)
+		assert.Equal(t, 1, code, args)
+		assert.Contains(t, stderr, "does not exist", args)
+	}
+	assert.NoFileExists(t, missing)
+
+	// diff 的对比目标拼错时报错，而不是与新建的空库比较
+	store := "sqlite:" + filepath.Join(dir, "config.db")
+	require.Equal(t, 0, run([]string{"-store", store, "set", "app.port", "8080"}, nil, &bytes.Buffer{}, &bytes.Buffer{}))
+	code, stdout, stderr := runCLI(t, "", "-store", store, "diff", filepath.Join(dir, "typo.db"))
+	assert.Equal(t, 1, code)
+	assert.Empty(t, stdout)
+	assert.Contains(t, stderr, "does not exist")
+	assert.NoFileExists(t, filepath.Join(dir, "typo.db"))
+}
+
+func TestRun_Usage(t *testing.T) {
+	code, _, stderr := runCLI(t, "")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "usage:")
+
+	store := "sqlite:" + filepath.Join(t.TempDir(), "config.db")
+	code, _, stderr = runCLI(t, "", "-store", store, "frobnicate")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "unknown command")
+
+	code, _, _ = runCLI(t, "", "-store", store, "get")
+	assert.Equal(t, 2, code)
+
+	code, _, stderr = runCLI(t, "", "-store", "mongo:localhost", "list")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "unsupported store")
+}
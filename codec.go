@@ -0,0 +1,186 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"gopkg.in/yaml.v3"
+)
+
+// Codec 定义复杂类型配置值的编解码方式
+type Codec interface {
+	// Name 返回编解码器名称，会随值一起记录在存储中
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// strictCodec 由支持严格解码（拒绝未知字段）的编解码器实现
+type strictCodec interface {
+	UnmarshalStrict(data []byte, v any) error
+}
+
+// 内置编解码器
+var (
+	JSONCodec    Codec = jsonCodec{}
+	YAMLCodec    Codec = yamlCodec{}
+	MsgpackCodec Codec = msgpackCodec{}
+	GobCodec     Codec = gobCodec{}
+)
+
+// 非 JSON 编码的值以 "#!<name>\n" 开头，二进制编码追加 ";base64" 标记，
+// JSON 编码的值保持原样，与历史数据兼容。
+const (
+	codecHeaderPrefix = "#!"
+	codecBase64Suffix = ";base64"
+)
+
+var (
+	codecMap   = make(map[string]Codec)
+	codecMutex sync.RWMutex
+)
+
+func init() {
+	for _, c := range []Codec{JSONCodec, YAMLCodec, MsgpackCodec, GobCodec} {
+		RegisterCodec(c)
+	}
+}
+
+// RegisterCodec 注册编解码器，读取时根据存储中记录的名称查找
+func RegisterCodec(c Codec) {
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+	codecMap[c.Name()] = c
+}
+
+func lookupCodec(name string) (Codec, bool) {
+	codecMutex.RLock()
+	defer codecMutex.RUnlock()
+	c, ok := codecMap[name]
+	return c, ok
+}
+
+type keyCodec struct {
+	pattern string
+	codec   Codec
+}
+
+// SetCodec 设置复杂类型默认使用的编解码器
+func (sm *SettingManager) SetCodec(c Codec) {
+	sm.codec = c
+}
+
+// SetKeyCodec 为匹配模式的键指定编解码器，优先于 SetCodec 的设置
+func (sm *SettingManager) SetKeyCodec(pattern string, c Codec) {
+	sm.keyCodecs = append(sm.keyCodecs, keyCodec{pattern: pattern, codec: c})
+}
+
+// codecFor 返回写入指定键时使用的编解码器
+func (sm *SettingManager) codecFor(key string) Codec {
+	for i := len(sm.keyCodecs) - 1; i >= 0; i-- {
+		if matchKey(sm.keyCodecs[i].pattern, key) {
+			return sm.keyCodecs[i].codec
+		}
+	}
+	if sm.codec != nil {
+		return sm.codec
+	}
+	return JSONCodec
+}
+
+// encodeWithCodec 使用编解码器编码值，并附加编码标记
+func encodeWithCodec(c Codec, value any) (string, error) {
+	data, err := c.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("%s marshal error: %w", c.Name(), err)
+	}
+	if c.Name() == JSONCodec.Name() {
+		return string(data), nil
+	}
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return codecHeaderPrefix + c.Name() + codecBase64Suffix + "\n" + base64.StdEncoding.EncodeToString(data), nil
+	}
+	return codecHeaderPrefix + c.Name() + "\n" + string(data), nil
+}
+
+// decodeComplex 根据存储值中的编码标记选择编解码器解析复杂类型
+func decodeComplex(value string, v any, mode DecodeMode) error {
+	header, payload, ok := strings.Cut(value, "\n")
+	if !ok || !strings.HasPrefix(header, codecHeaderPrefix) {
+		return decodeJSON([]byte(value), v, mode)
+	}
+
+	name := strings.TrimPrefix(header, codecHeaderPrefix)
+	data := []byte(payload)
+	if trimmed, isBase64 := strings.CutSuffix(name, codecBase64Suffix); isBase64 {
+		name = trimmed
+		decoded, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return fmt.Errorf("decode %s payload error: %w", name, err)
+		}
+		data = decoded
+	}
+
+	c, ok := lookupCodec(name)
+	if !ok {
+		return fmt.Errorf("unknown codec %q", name)
+	}
+	if sc, ok := c.(strictCodec); ok && mode == DecodeStrict {
+		return sc.UnmarshalStrict(data, v)
+	}
+	return c.Unmarshal(data, v)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                       { return "json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) UnmarshalStrict(data []byte, v any) error {
+	return decodeJSON(data, v, DecodeStrict)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Name() string                       { return "yaml" }
+func (yamlCodec) Marshal(v any) ([]byte, error)      { return yaml.Marshal(v) }
+func (yamlCodec) Unmarshal(data []byte, v any) error { return yaml.Unmarshal(data, v) }
+func (yamlCodec) UnmarshalStrict(data []byte, v any) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(v)
+}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string                       { return "msgpack" }
+func (msgpackCodec) Marshal(v any) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v any) error { return msgpack.Unmarshal(data, v) }
+func (msgpackCodec) UnmarshalStrict(data []byte, v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields(true)
+	return dec.Decode(v)
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string { return "gob" }
+
+func (gobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (gobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
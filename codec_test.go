@@ -0,0 +1,69 @@
+package conf
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type codecTestConfig struct {
+	Host string `json:"host" yaml:"host" msgpack:"host"`
+	Port int    `json:"port" yaml:"port" msgpack:"port"`
+}
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	cfg := codecTestConfig{Host: "localhost", Port: 5432}
+
+	for _, c := range []Codec{JSONCodec, YAMLCodec, MsgpackCodec, GobCodec} {
+		t.Run(c.Name(), func(t *testing.T) {
+			manager := newSettingManager(newMockStorage())
+			manager.SetCodec(c)
+
+			require.NoError(t, manager.Set("database", cfg))
+
+			got, err := getTyped[codecTestConfig](manager, "database")
+			require.NoError(t, err)
+			assert.Equal(t, cfg, *got)
+		})
+	}
+}
+
+func TestCodecs_StoredFormat(t *testing.T) {
+	storage := newMockStorage()
+	manager := newSettingManager(storage)
+	manager.SetKeyCodec("yaml.*", YAMLCodec)
+	manager.SetKeyCodec("binary.*", MsgpackCodec)
+
+	cfg := codecTestConfig{Host: "localhost", Port: 5432}
+	require.NoError(t, manager.Set("plain", cfg))
+	require.NoError(t, manager.Set("yaml.database", cfg))
+	require.NoError(t, manager.Set("binary.database", cfg))
+
+	// JSON 保持原有格式，其余编码记录编解码器名称
+	assert.Equal(t, `{"host":"localhost","port":5432}`, storage.data["plain"])
+	assert.Equal(t, "#!yaml\nhost: localhost\nport: 5432\n", storage.data["yaml.database"])
+	assert.True(t, strings.HasPrefix(storage.data["binary.database"], "#!msgpack;base64\n"))
+
+	// 混合编码的数据即使绕过缓存也能正确解码
+	reader := newSettingManager(storage)
+	for _, key := range []string{"plain", "yaml.database", "binary.database"} {
+		got, err := getTyped[codecTestConfig](reader, key)
+		require.NoError(t, err, key)
+		assert.Equal(t, cfg, *got, key)
+	}
+}
+
+func TestCodecs_Strict(t *testing.T) {
+	value := "#!yaml\nhost: localhost\nport: 5432\nextra: true\n"
+
+	_, err := parseValueMode[codecTestConfig](value, DecodeDefault)
+	require.NoError(t, err)
+
+	_, err = parseValueMode[codecTestConfig](value, DecodeStrict)
+	assert.Error(t, err)
+
+	_, err = parseValueMode[codecTestConfig]("#!unknown\n{}", DecodeDefault)
+	assert.Error(t, err)
+}
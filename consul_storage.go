@@ -0,0 +1,387 @@
+package conf
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsulOptions Consul KV 存储的配置
+type ConsulOptions struct {
+	// Address Consul agent 地址，默认为 "http://127.0.0.1:8500"
+	Address string
+	// Prefix KV 路径前缀，例如 "myapp/"
+	Prefix string
+	// Token ACL token
+	Token string
+	// Datacenter 数据中心，为空时使用 agent 所在的数据中心
+	Datacenter string
+	// HTTPClient 自定义 HTTP 客户端，默认为 http.DefaultClient
+	HTTPClient *http.Client
+	// WaitTime 阻塞查询的最长等待时间，默认为 5 分钟
+	WaitTime time.Duration
+	// Timeout 单次请求的超时时间，默认为 5 秒；阻塞查询在 WaitTime 的基础上额外等待该时间
+	Timeout time.Duration
+}
+
+// ConsulStorage 基于 Consul HTTP KV API 的存储。
+// 配置键映射为 KV 路径（app.db.host ↔ app/db/host），使用 ModifyIndex 作为版本号支持 CAS，
+// 并通过阻塞查询感知变更。
+type ConsulStorage struct {
+	opts     ConsulOptions
+	notifier changeNotifier
+
+	watchOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// consulKVPair 是 Consul KV 接口返回的条目
+type consulKVPair struct {
+	Key         string
+	Value       string
+	ModifyIndex int64
+}
+
+// NewConsulStorage 创建 Consul KV 存储
+func NewConsulStorage(opts ConsulOptions) *ConsulStorage {
+	if opts.Address == "" {
+		opts.Address = "http://127.0.0.1:8500"
+	}
+	opts.Address = strings.TrimRight(opts.Address, "/")
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = http.DefaultClient
+	}
+	if opts.WaitTime <= 0 {
+		opts.WaitTime = 5 * time.Minute
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ConsulStorage{opts: opts, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+}
+
+// consulPath 将配置键转换为 KV 路径
+func (cs *ConsulStorage) consulPath(key string) string {
+	return cs.opts.Prefix + strings.ReplaceAll(key, ".", "/")
+}
+
+// settingKey 将 KV 路径转换为配置键
+func (cs *ConsulStorage) settingKey(path string) string {
+	return strings.ReplaceAll(strings.TrimPrefix(path, cs.opts.Prefix), "/", ".")
+}
+
+func (cs *ConsulStorage) requestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(cs.ctx, cs.opts.Timeout)
+}
+
+// do 发送 KV 请求，返回响应体、X-Consul-Index 和状态码
+func (cs *ConsulStorage) do(ctx context.Context, method, path string, query url.Values, body io.Reader) ([]byte, int64, int, error) {
+	return cs.doAPI(ctx, method, "kv/"+(&url.URL{Path: path}).EscapedPath(), query, body)
+}
+
+// doAPI 向 /v1/<endpoint> 发送请求，200、404 和 409（事务回滚）以外的状态码视为错误
+func (cs *ConsulStorage) doAPI(ctx context.Context, method, endpoint string, query url.Values, body io.Reader) ([]byte, int64, int, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if cs.opts.Datacenter != "" {
+		query.Set("dc", cs.opts.Datacenter)
+	}
+
+	u := cs.opts.Address + "/v1/" + endpoint
+	if encoded := query.Encode(); encoded != "" {
+		u += "?" + encoded
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if cs.opts.Token != "" {
+		req.Header.Set("X-Consul-Token", cs.opts.Token)
+	}
+
+	resp, err := cs.opts.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, resp.StatusCode, err
+	}
+	index, _ := strconv.ParseInt(resp.Header.Get("X-Consul-Index"), 10, 64)
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNotFound, http.StatusConflict:
+	default:
+		return nil, index, resp.StatusCode, fmt.Errorf("consul %s %s: %s: %s", method, endpoint, resp.Status, strings.TrimSpace(string(data)))
+	}
+	return data, index, resp.StatusCode, nil
+}
+
+func (cs *ConsulStorage) Get(key string) (string, error) {
+	value, _, err := cs.GetVersion(key)
+	return value, err
+}
+
+// GetVersion 返回键的值及其 ModifyIndex
+func (cs *ConsulStorage) GetVersion(key string) (string, int64, error) {
+	ctx, cancel := cs.requestContext()
+	defer cancel()
+
+	data, _, status, err := cs.do(ctx, http.MethodGet, cs.consulPath(key), nil, nil)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if status == http.StatusNotFound {
+		return "", 0, ErrKeyNotFound
+	}
+
+	var pairs []consulKVPair
+	if err := json.Unmarshal(data, &pairs); err != nil || len(pairs) == 0 {
+		return "", 0, fmt.Errorf("%w: invalid consul response: %v", ErrStorageOperation, err)
+	}
+	value, err := base64.StdEncoding.DecodeString(pairs[0].Value)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return string(value), pairs[0].ModifyIndex, nil
+}
+
+func (cs *ConsulStorage) Set(key, value string) error {
+	_, err := cs.put(key, value, nil)
+	return err
+}
+
+// consulTxnOp 是 /v1/txn 中的一个 KV 操作
+type consulTxnOp struct {
+	KV consulTxnKV
+}
+
+type consulTxnKV struct {
+	Verb  string
+	Key   string
+	Value string `json:",omitempty"`
+	Index int64  `json:",omitempty"`
+}
+
+// consulTxnResult 是 /v1/txn 的响应
+type consulTxnResult struct {
+	Results []struct {
+		KV consulKVPair
+	}
+	Errors []struct {
+		OpIndex int
+		What    string
+	}
+}
+
+// CompareAndSet 仅当键的 ModifyIndex 等于 version 时写入，version 为 0 表示键必须不存在。
+// 写入和读取新的 ModifyIndex 在同一个 KV 事务中完成，返回的版本号不会被并发写入影响。
+func (cs *ConsulStorage) CompareAndSet(key, value string, version int64) (int64, error) {
+	path := cs.consulPath(key)
+	ops, err := json.Marshal([]consulTxnOp{
+		{KV: consulTxnKV{Verb: "cas", Key: path, Value: base64.StdEncoding.EncodeToString([]byte(value)), Index: version}},
+		{KV: consulTxnKV{Verb: "get", Key: path}},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+
+	ctx, cancel := cs.requestContext()
+	defer cancel()
+	data, _, status, err := cs.doAPI(ctx, http.MethodPut, "txn", nil, bytes.NewReader(ops))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+
+	var result consulTxnResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return 0, fmt.Errorf("%w: invalid consul response: %v", ErrStorageOperation, err)
+	}
+	if status == http.StatusConflict {
+		// 事务回滚：CAS 条件不满足时 Errors 指向第一个操作
+		if len(result.Errors) > 0 && result.Errors[0].OpIndex == 0 {
+			return 0, ErrVersionConflict
+		}
+		return 0, fmt.Errorf("%w: consul transaction failed: %v", ErrStorageOperation, result.Errors)
+	}
+	if len(result.Results) != 2 {
+		return 0, fmt.Errorf("%w: invalid consul response", ErrStorageOperation)
+	}
+	return result.Results[1].KV.ModifyIndex, nil
+}
+
+func (cs *ConsulStorage) put(key, value string, query url.Values) (bool, error) {
+	ctx, cancel := cs.requestContext()
+	defer cancel()
+
+	data, _, _, err := cs.do(ctx, http.MethodPut, cs.consulPath(key), query, strings.NewReader(value))
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return strings.TrimSpace(string(data)) == "true", nil
+}
+
+// CompareAndDelete 仅当键的 ModifyIndex 等于 version 时删除
+func (cs *ConsulStorage) CompareAndDelete(key string, version int64) error {
+	if version <= 0 {
+		return ErrVersionConflict
+	}
+	ctx, cancel := cs.requestContext()
+	defer cancel()
+
+	query := url.Values{"cas": {strconv.FormatInt(version, 10)}}
+	data, _, _, err := cs.do(ctx, http.MethodDelete, cs.consulPath(key), query, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if strings.TrimSpace(string(data)) != "true" {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (cs *ConsulStorage) Delete(key string) error {
+	ctx, cancel := cs.requestContext()
+	defer cancel()
+
+	if _, _, _, err := cs.do(ctx, http.MethodDelete, cs.consulPath(key), nil, nil); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+// Keys 返回以 prefix 开头的所有键
+func (cs *ConsulStorage) Keys(prefix string) ([]string, error) {
+	ctx, cancel := cs.requestContext()
+	defer cancel()
+
+	data, _, status, err := cs.do(ctx, http.MethodGet, cs.consulPath(prefix), url.Values{"keys": {""}}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if status == http.StatusNotFound {
+		return nil, nil
+	}
+
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return nil, fmt.Errorf("%w: invalid consul response: %v", ErrStorageOperation, err)
+	}
+
+	keys := make([]string, 0, len(paths))
+	for _, p := range paths {
+		// 以 "/" 结尾的是目录占位条目
+		if !strings.HasSuffix(p, "/") {
+			keys = append(keys, cs.settingKey(p))
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Watch 注册变更回调，第一次调用时开始对前缀发起阻塞查询
+func (cs *ConsulStorage) Watch(fn func(key string)) (cancel func()) {
+	cancel = cs.notifier.Watch(fn)
+	cs.watchOnce.Do(func() { go cs.watchLoop() })
+	return cancel
+}
+
+// fetchAll 读取前缀下的所有条目，index 大于 0 时发起阻塞查询
+func (cs *ConsulStorage) fetchAll(index int64) (int64, map[string]int64, error) {
+	query := url.Values{"recurse": {""}}
+	timeout := cs.opts.Timeout
+	if index > 0 {
+		query.Set("index", strconv.FormatInt(index, 10))
+		query.Set("wait", cs.opts.WaitTime.String())
+		// Consul 会在 wait 的基础上增加最多 wait/16 的随机抖动
+		timeout += cs.opts.WaitTime + cs.opts.WaitTime/16
+	}
+	ctx, cancel := context.WithTimeout(cs.ctx, timeout)
+	defer cancel()
+
+	data, newIndex, status, err := cs.do(ctx, http.MethodGet, cs.opts.Prefix, query, nil)
+	if err != nil {
+		return index, nil, err
+	}
+
+	snapshot := make(map[string]int64)
+	if status == http.StatusOK {
+		var pairs []consulKVPair
+		if err := json.Unmarshal(data, &pairs); err != nil {
+			return index, nil, err
+		}
+		for _, pair := range pairs {
+			if !strings.HasSuffix(pair.Key, "/") {
+				snapshot[cs.settingKey(pair.Key)] = pair.ModifyIndex
+			}
+		}
+	}
+	return newIndex, snapshot, nil
+}
+
+// watchLoop 先在后台获取当前状态作为基线（避免将已有的键报告为变更），再持续发起阻塞查询
+func (cs *ConsulStorage) watchLoop() {
+	defer close(cs.done)
+
+	var (
+		index    int64
+		snapshot map[string]int64
+	)
+	for cs.ctx.Err() == nil {
+		newIndex, current, err := cs.fetchAll(index)
+		if err != nil {
+			select {
+			case <-cs.ctx.Done():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+
+		// 索引回退时需要重新开始，索引无效时从 1 开始阻塞，避免空转
+		if newIndex < index {
+			newIndex = 0
+		} else if newIndex < 1 {
+			newIndex = 1
+		}
+		if snapshot == nil {
+			// 基线建立前的读取可能已经过期，让所有缓存失效
+			cs.notifier.notify("")
+		} else {
+			for key, modifyIndex := range current {
+				if old, ok := snapshot[key]; !ok || old != modifyIndex {
+					cs.notifier.notify(key)
+				}
+			}
+			for key := range snapshot {
+				if _, ok := current[key]; !ok {
+					cs.notifier.notify(key)
+				}
+			}
+		}
+		index, snapshot = newIndex, current
+	}
+}
+
+// Close 停止阻塞查询并取消未完成的请求
+func (cs *ConsulStorage) Close() error {
+	cs.cancel()
+	// 如果从未开始 watch，在这里关闭 done
+	cs.watchOnce.Do(func() { close(cs.done) })
+	<-cs.done
+	return nil
+}
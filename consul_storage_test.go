@@ -0,0 +1,269 @@
+package conf
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConsul 模拟 Consul 的 KV 接口，包括 CAS 和阻塞查询
+type fakeConsul struct {
+	mutex   sync.Mutex
+	changed *sync.Cond
+	index   int64
+	data    map[string]consulKVPair
+	token   string
+}
+
+func newFakeConsul(t *testing.T, token string) *httptest.Server {
+	t.Helper()
+
+	fc := &fakeConsul{index: 1, data: make(map[string]consulKVPair), token: token}
+	fc.changed = sync.NewCond(&fc.mutex)
+	server := httptest.NewServer(fc)
+	t.Cleanup(func() {
+		// 唤醒仍在阻塞的查询
+		fc.mutex.Lock()
+		fc.index++
+		fc.changed.Broadcast()
+		fc.mutex.Unlock()
+		server.Close()
+	})
+	return server
+}
+
+func (fc *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if fc.token != "" && r.Header.Get("X-Consul-Token") != fc.token {
+		http.Error(w, "ACL not found", http.StatusForbidden)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	query := r.URL.Query()
+
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if r.URL.Path == "/v1/txn" {
+		fc.serveTxn(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		if cas := query.Get("cas"); cas != "" {
+			want, _ := strconv.ParseInt(cas, 10, 64)
+			if fc.data[key].ModifyIndex != want {
+				_, _ = io.WriteString(w, "false")
+				return
+			}
+		}
+		fc.index++
+		fc.data[key] = consulKVPair{Key: key, Value: base64.StdEncoding.EncodeToString(body), ModifyIndex: fc.index}
+		fc.changed.Broadcast()
+		_, _ = io.WriteString(w, "true")
+	case http.MethodDelete:
+		if cas := query.Get("cas"); cas != "" {
+			want, _ := strconv.ParseInt(cas, 10, 64)
+			if fc.data[key].ModifyIndex != want {
+				_, _ = io.WriteString(w, "false")
+				return
+			}
+		}
+		fc.index++
+		delete(fc.data, key)
+		fc.changed.Broadcast()
+		_, _ = io.WriteString(w, "true")
+	case http.MethodGet:
+		if index, _ := strconv.ParseInt(query.Get("index"), 10, 64); index > 0 {
+			deadline := time.Now().Add(2 * time.Second)
+			for fc.index <= index && time.Now().Before(deadline) {
+				fc.changed.Wait()
+			}
+		}
+		w.Header().Set("X-Consul-Index", strconv.FormatInt(fc.index, 10))
+
+		var pairs []consulKVPair
+		if query.Has("recurse") || query.Has("keys") {
+			for k, pair := range fc.data {
+				if strings.HasPrefix(k, key) {
+					pairs = append(pairs, pair)
+				}
+			}
+		} else if pair, ok := fc.data[key]; ok {
+			pairs = append(pairs, pair)
+		}
+		if len(pairs) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		sort.Slice(pairs, func(i, j int) bool { return pairs[i].Key < pairs[j].Key })
+
+		if query.Has("keys") {
+			keys := make([]string, 0, len(pairs))
+			for _, pair := range pairs {
+				keys = append(keys, pair.Key)
+			}
+			_ = json.NewEncoder(w).Encode(keys)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(pairs)
+	}
+}
+
+// serveTxn 支持 cas 和 get 两种操作，任一操作失败时整个事务回滚
+func (fc *fakeConsul) serveTxn(w http.ResponseWriter, r *http.Request) {
+	var ops []consulTxnOp
+	if err := json.NewDecoder(r.Body).Decode(&ops); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type result struct {
+		KV consulKVPair
+	}
+	var results []result
+	staged := make(map[string]consulKVPair)
+	index := fc.index
+	for i, op := range ops {
+		pair, ok := staged[op.KV.Key]
+		if !ok {
+			pair = fc.data[op.KV.Key]
+		}
+		switch op.KV.Verb {
+		case "cas":
+			if pair.ModifyIndex != op.KV.Index {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"Errors": []map[string]any{{"OpIndex": i, "What": "failed to set key"}},
+				})
+				return
+			}
+			index++
+			pair = consulKVPair{Key: op.KV.Key, Value: op.KV.Value, ModifyIndex: index}
+			staged[op.KV.Key] = pair
+		case "get":
+			if pair.ModifyIndex == 0 {
+				w.WriteHeader(http.StatusConflict)
+				_ = json.NewEncoder(w).Encode(map[string]any{
+					"Errors": []map[string]any{{"OpIndex": i, "What": "key does not exist"}},
+				})
+				return
+			}
+		}
+		results = append(results, result{KV: pair})
+	}
+
+	for k, pair := range staged {
+		fc.data[k] = pair
+	}
+	fc.index = index
+	fc.changed.Broadcast()
+	_ = json.NewEncoder(w).Encode(map[string]any{"Results": results})
+}
+
+func TestConsulStorage(t *testing.T) {
+	server := newFakeConsul(t, "secret")
+	opts := ConsulOptions{Address: server.URL, Prefix: "myapp/", Token: "secret"}
+
+	t.Run("basic operations", func(t *testing.T) {
+		storage := NewConsulStorage(opts)
+		defer storage.Close()
+
+		_, err := storage.Get("app.name")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+
+		require.NoError(t, storage.Set("app.name", "demo"))
+		require.NoError(t, storage.Set("app.db.host", "localhost"))
+		require.NoError(t, storage.Set("app.db.port", "5432"))
+
+		value, err := storage.Get("app.db.host")
+		require.NoError(t, err)
+		assert.Equal(t, "localhost", value)
+
+		keys, err := storage.Keys("app.db.")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app.db.host", "app.db.port"}, keys)
+
+		require.NoError(t, storage.Delete("app.name"))
+		_, err = storage.Get("app.name")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("compare and swap", func(t *testing.T) {
+		storage := NewConsulStorage(opts)
+		defer storage.Close()
+
+		version, err := storage.CompareAndSet("lock.owner", "a", 0)
+		require.NoError(t, err)
+		value, current, err := storage.GetVersion("lock.owner")
+		require.NoError(t, err)
+		assert.Equal(t, "a", value)
+		assert.Equal(t, current, version)
+
+		_, err = storage.CompareAndSet("lock.owner", "b", 0)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+
+		_, err = storage.CompareAndSet("lock.owner", "b", version)
+		require.NoError(t, err)
+
+		_, err = storage.CompareAndSet("lock.owner", "c", version)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+	})
+
+	t.Run("blocking query invalidates cache", func(t *testing.T) {
+		writer := NewConsulStorage(opts)
+		reader := NewConsulStorage(opts)
+		defer writer.Close()
+		defer reader.Close()
+
+		require.NoError(t, writer.Set("feature.enabled", "true"))
+		manager := newSettingManager(reader)
+
+		value, err := getTyped[bool](manager, "feature.enabled")
+		require.NoError(t, err)
+		assert.True(t, *value)
+
+		require.NoError(t, writer.Set("feature.enabled", "false"))
+		assert.Eventually(t, func() bool {
+			value, err := getTyped[bool](manager, "feature.enabled")
+			return err == nil && !*value
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("unreachable agent", func(t *testing.T) {
+		unreachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(time.Second)
+		}))
+		defer unreachable.Close()
+		storage := NewConsulStorage(ConsulOptions{Address: unreachable.URL, Timeout: 50 * time.Millisecond})
+		defer storage.Close()
+
+		// Watch 在后台建立基线，请求按 Timeout 超时
+		start := time.Now()
+		newSettingManager(storage)
+		_, err := storage.Get("app.name")
+		assert.ErrorIs(t, err, ErrStorageOperation)
+		assert.Less(t, time.Since(start), 500*time.Millisecond)
+	})
+
+	t.Run("permission denied", func(t *testing.T) {
+		storage := NewConsulStorage(ConsulOptions{Address: server.URL, Prefix: "myapp/"})
+		defer storage.Close()
+
+		_, err := storage.Get("app.name")
+		assert.ErrorIs(t, err, ErrStorageOperation)
+	})
+}
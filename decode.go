@@ -0,0 +1,154 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// DecodeMode 控制字符串配置值转换为目标类型时的严格程度
+type DecodeMode int
+
+const (
+	// DecodeDefault 保持原有行为：标准库解析基础类型，JSON 解析复杂类型
+	DecodeDefault DecodeMode = iota
+	// DecodeStrict 拒绝未知的结构体字段以及浮点数到整数的转换，适合在 CI 中发现错误配置
+	DecodeStrict
+	// DecodeLenient 去除首尾空白，接受 yes/no/on/off 布尔值以及 "1e3" 形式的整数
+	DecodeLenient
+)
+
+// String 返回解码模式的名称
+func (m DecodeMode) String() string {
+	switch m {
+	case DecodeDefault:
+		return "default"
+	case DecodeStrict:
+		return "strict"
+	case DecodeLenient:
+		return "lenient"
+	default:
+		return fmt.Sprintf("DecodeMode(%d)", int(m))
+	}
+}
+
+// SetDecodeMode 设置类型化读取时使用的解码模式
+func (sm *SettingManager) SetDecodeMode(mode DecodeMode) {
+	sm.decodeMode = mode
+}
+
+// DecodeMode 返回当前的解码模式
+func (sm *SettingManager) DecodeMode() DecodeMode {
+	return sm.decodeMode
+}
+
+// 宽松模式下优先使用的类型转换器
+var lenientParserMap = make(map[string]any)
+
+func init() {
+	registerLenientParser[bool](parseLenientBool)
+	registerLenientParser[int](func(v string) (int, error) {
+		n, err := parseLenientInt(v, strconv.IntSize)
+		return int(n), err
+	})
+	registerLenientParser[int64](func(v string) (int64, error) { return parseLenientInt(v, 64) })
+}
+
+// registerLenientParser 注册宽松模式的类型转换器
+func registerLenientParser[T any](parser TypeParser[T]) {
+	t := new(T)
+	lenientParserMap[fmt.Sprintf("%T", *t)] = parser
+}
+
+// parseLenientBool 在 strconv.ParseBool 的基础上接受 yes/no/on/off/y/n
+func parseLenientBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "yes", "y", "on":
+		return true, nil
+	case "no", "n", "off":
+		return false, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// parseLenientInt 接受十进制整数以及能精确表示为整数的浮点写法，例如 "1e3"、"42.0"
+func parseLenientInt(v string, bitSize int) (int64, error) {
+	n, err := strconv.ParseInt(v, 10, bitSize)
+	if err == nil {
+		return n, nil
+	}
+	f, ferr := strconv.ParseFloat(v, 64)
+	if ferr != nil {
+		return 0, err
+	}
+	limit := math.Ldexp(1, bitSize-1)
+	if f != math.Trunc(f) || f < -limit || f >= limit {
+		return 0, fmt.Errorf("%w: %q is not an integral value", ErrTypeConversion, v)
+	}
+	return int64(f), nil
+}
+
+// decodeJSON 按解码模式将 JSON 数据解析到 v 中
+func decodeJSON(data []byte, v any, mode DecodeMode) error {
+	if mode != DecodeStrict {
+		return json.Unmarshal(data, v)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	// 与 json.Unmarshal 保持一致，拒绝多余的尾部数据
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errors.New("unexpected data after top-level value")
+	}
+	return nil
+}
+
+// convertValue 将非字符串的原始值（例如来自 viper 的值）转换为目标类型
+func convertValue[T any](value any, mode DecodeMode) (*T, error) {
+	var result T
+
+	if mode == DecodeStrict && isFloatValue(value) && isIntegerType(reflect.TypeOf(result)) {
+		return nil, fmt.Errorf("%w: refusing to convert %T to %T in strict mode", ErrTypeConversion, value, result)
+	}
+
+	// 尝试通过 JSON 转换
+	jsonData, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("marshal error: %w", err)
+	}
+
+	if err := decodeJSON(jsonData, &result, mode); err != nil {
+		return nil, fmt.Errorf("unmarshal error: %w", err)
+	}
+
+	return &result, nil
+}
+
+func isFloatValue(value any) bool {
+	switch value.(type) {
+	case float32, float64:
+		return true
+	}
+	return false
+}
+
+func isIntegerType(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseValueMode(t *testing.T) {
+	type Server struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+
+	t.Run("lenient booleans", func(t *testing.T) {
+		for input, want := range map[string]bool{
+			"yes": true, "On": true, " y ": true, "true": true,
+			"no": false, "OFF": false, "n": false, "0": false,
+		} {
+			got, err := parseValueMode[bool](input, DecodeLenient)
+			require.NoError(t, err, input)
+			assert.Equal(t, want, *got, input)
+		}
+
+		_, err := parseValueMode[bool]("yes", DecodeDefault)
+		assert.Error(t, err)
+	})
+
+	t.Run("lenient integers", func(t *testing.T) {
+		got, err := parseValueMode[int]("1e3", DecodeLenient)
+		require.NoError(t, err)
+		assert.Equal(t, 1000, *got)
+
+		got64, err := parseValueMode[int64](" 42.0\n", DecodeLenient)
+		require.NoError(t, err)
+		assert.Equal(t, int64(42), *got64)
+
+		// 非整数值不会被截断
+		_, err = parseValueMode[int]("3.7", DecodeLenient)
+		assert.ErrorIs(t, err, ErrTypeConversion)
+
+		_, err = parseValueMode[int]("1e3", DecodeStrict)
+		assert.Error(t, err)
+	})
+
+	t.Run("lenient trims whitespace", func(t *testing.T) {
+		got, err := parseValueMode[float64](" 2.5 ", DecodeLenient)
+		require.NoError(t, err)
+		assert.Equal(t, 2.5, *got)
+
+		_, err = parseValueMode[float64](" 2.5 ", DecodeDefault)
+		assert.Error(t, err)
+	})
+
+	t.Run("strict rejects unknown fields", func(t *testing.T) {
+		input := `{"host":"localhost","port":80,"hots":"typo"}`
+
+		got, err := parseValueMode[Server](input, DecodeDefault)
+		require.NoError(t, err)
+		assert.Equal(t, Server{Host: "localhost", Port: 80}, *got)
+
+		_, err = parseValueMode[Server](input, DecodeStrict)
+		assert.Error(t, err)
+
+		_, err = parseValueMode[Server](`{"host":"a"} {}`, DecodeStrict)
+		assert.Error(t, err)
+	})
+}
+
+func TestConvertValue_Strict(t *testing.T) {
+	got, err := convertValue[int](float64(80), DecodeDefault)
+	require.NoError(t, err)
+	assert.Equal(t, 80, *got)
+
+	_, err = convertValue[int](float64(80), DecodeStrict)
+	assert.ErrorIs(t, err, ErrTypeConversion)
+}
+
+func TestSettingManager_DecodeMode(t *testing.T) {
+	manager := newSettingManager(newMockStorage())
+	require.NoError(t, manager.Set("feature.enabled", "on"))
+
+	_, err := getTyped[bool](manager, "feature.enabled")
+	assert.Error(t, err)
+
+	manager.SetDecodeMode(DecodeLenient)
+	assert.Equal(t, DecodeLenient, manager.DecodeMode())
+
+	got, err := getTyped[bool](manager, "feature.enabled")
+	require.NoError(t, err)
+	assert.True(t, *got)
+}
@@ -0,0 +1,250 @@
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DiffResult 描述从存储 a 到存储 b 的差异
+type DiffResult struct {
+	// Added 只存在于 b 中的键
+	Added []SyncChange `json:"added"`
+	// Removed 只存在于 a 中的键
+	Removed []SyncChange `json:"removed"`
+	// Changed 两边值不同的键
+	Changed []SyncChange `json:"changed"`
+}
+
+// Empty 判断两边是否没有差异
+func (d *DiffResult) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffOptions 配置差异比较
+type DiffOptions struct {
+	// TextKeys 按原文比较的键模式（语法同 matchKey），用于前导零有意义的编码或版本号等
+	// 看起来像数字的字符串，例如 "007" 与 "7"、"1.10" 与 "1.1"
+	TextKeys []string
+}
+
+// Diff 比较两个存储中以 prefix 开头的键，两个存储都需要实现 SettingLister。
+// 值按类型比较：数字、布尔值、时间、时长和 JSON 在语义相同时不算变化，
+// 例如 "1.0" 与 "1"、"60s" 与 "1m" 不是变化。
+func Diff(a, b SettingStorage, prefix string) (*DiffResult, error) {
+	return DiffWith(a, b, prefix, DiffOptions{})
+}
+
+// DiffWith 与 Diff 相同，opts 指定按原文比较的键
+func DiffWith(a, b SettingStorage, prefix string, opts DiffOptions) (*DiffResult, error) {
+	from, err := readPrefix(a, prefix)
+	if err != nil {
+		return nil, err
+	}
+	to, err := readPrefix(b, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &DiffResult{}
+	for _, key := range sortedKeys(from) {
+		newValue, ok := to[key]
+		switch {
+		case !ok:
+			result.Removed = append(result.Removed, SyncChange{Key: key, OldValue: from[key]})
+		case !opts.equal(key, from[key], newValue):
+			result.Changed = append(result.Changed, SyncChange{Key: key, OldValue: from[key], NewValue: newValue})
+		}
+	}
+	for _, key := range sortedKeys(to) {
+		if _, ok := from[key]; !ok {
+			result.Added = append(result.Added, SyncChange{Key: key, NewValue: to[key]})
+		}
+	}
+	return result, nil
+}
+
+// equal 比较键的两个值，TextKeys 中的键按原文比较
+func (opts DiffOptions) equal(key, a, b string) bool {
+	if matchAnyKey(opts.TextKeys, key) {
+		return a == b
+	}
+	return valuesEqual(a, b)
+}
+
+// Diff 比较管理器的存储层与 other，敏感键的值会被隐藏
+func (sm *SettingManager) Diff(other SettingStorage, prefix string) (*DiffResult, error) {
+	result, err := Diff(sm.storage, other, prefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, changes := range [][]SyncChange{result.Added, result.Removed, result.Changed} {
+		for i := range changes {
+			if sm.IsSensitive(changes[i].Key) {
+				if changes[i].OldValue != "" {
+					changes[i].OldValue = RedactedValue
+				}
+				if changes[i].NewValue != "" {
+					changes[i].NewValue = RedactedValue
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// readPrefix 读取存储中以 prefix 开头的所有配置
+func readPrefix(storage SettingStorage, prefix string) (map[string]string, error) {
+	lister, ok := storage.(SettingLister)
+	if !ok {
+		return nil, fmt.Errorf("%w: storage does not support listing", ErrStorageOperation)
+	}
+	keys, err := lister.Keys(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, err := storage.Get(key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// valuesEqual 按推断的类型比较两个存储值，依次尝试数字、布尔值、时间、时长和 JSON，
+// 任意一边能按某种类型解析时即由该类型决定结果
+func valuesEqual(a, b string) bool {
+	if a == b {
+		return true
+	}
+
+	if equal, decided := numbersEqual(a, b); decided {
+		return equal
+	}
+	if equal, decided := compareAs(a, b, strconv.ParseBool,
+		func(x, y bool) bool { return x == y }); decided {
+		return equal
+	}
+	if equal, decided := compareAs(a, b, func(s string) (time.Time, error) { return time.Parse(time.RFC3339Nano, s) },
+		time.Time.Equal); decided {
+		return equal
+	}
+	if equal, decided := compareAs(a, b, time.ParseDuration,
+		func(x, y time.Duration) bool { return x == y }); decided {
+		return equal
+	}
+	if isJSONContainer(a) && isJSONContainer(b) {
+		var x, y any
+		if json.Unmarshal([]byte(a), &x) == nil && json.Unmarshal([]byte(b), &y) == nil {
+			return reflect.DeepEqual(x, y)
+		}
+	}
+	return false
+}
+
+// numbersEqual 两边都是十进制数字时按精确的数值比较，例如 "1.0" 与 "1"、"1e3" 与 "1000" 相等。
+// 使用 big.Rat 而不是 float64，超过 float64 精度的 int64 ID 不会被视为相同。
+func numbersEqual(a, b string) (equal, decided bool) {
+	x, okA := parseNumber(a)
+	y, okB := parseNumber(b)
+	switch {
+	case okA && okB:
+		return x.Cmp(y) == 0, true
+	case okA || okB:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// parseNumber 将 strconv.ParseFloat 接受的数字解析为精确的有理数，不接受 NaN 和无穷大
+func parseNumber(s string) (*big.Rat, bool) {
+	if _, err := strconv.ParseFloat(s, 64); err != nil && !errors.Is(err, strconv.ErrRange) {
+		return nil, false
+	}
+	return new(big.Rat).SetString(s)
+}
+
+// compareAs 尝试按同一类型解析两个值，decided 为 false 表示两边都不是该类型
+func compareAs[T any](a, b string, parse func(string) (T, error), eq func(x, y T) bool) (equal, decided bool) {
+	x, errA := parse(a)
+	y, errB := parse(b)
+	switch {
+	case errA == nil && errB == nil:
+		return eq(x, y), true
+	case errA == nil || errB == nil:
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+func isJSONContainer(s string) bool {
+	return strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[")
+}
+
+// WriteUnified 以统一差异格式输出，fromName 和 toName 为两个存储的名称
+func (d *DiffResult) WriteUnified(w io.Writer, fromName, toName string) error {
+	type line struct {
+		key  string
+		text string
+	}
+	var lines []line
+	for _, c := range d.Removed {
+		lines = append(lines, line{c.Key, fmt.Sprintf("-%s = %s\n", c.Key, quoteDiffValue(c.OldValue))})
+	}
+	for _, c := range d.Added {
+		lines = append(lines, line{c.Key, fmt.Sprintf("+%s = %s\n", c.Key, quoteDiffValue(c.NewValue))})
+	}
+	for _, c := range d.Changed {
+		lines = append(lines, line{c.Key, fmt.Sprintf("-%s = %s\n+%s = %s\n",
+			c.Key, quoteDiffValue(c.OldValue), c.Key, quoteDiffValue(c.NewValue))})
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].key < lines[j].key })
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", fromName, toName)
+	for _, l := range lines {
+		b.WriteString(l.text)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// String 以统一差异格式返回差异
+func (d *DiffResult) String() string {
+	var b strings.Builder
+	_ = d.WriteUnified(&b, "a", "b")
+	return b.String()
+}
+
+// quoteDiffValue 对包含换行或首尾空白的值加引号，保证一行一个键
+func quoteDiffValue(value string) string {
+	if strings.ContainsAny(value, "\n\r") || strings.TrimSpace(value) != value {
+		return strconv.Quote(value)
+	}
+	return value
+}
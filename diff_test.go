@@ -0,0 +1,121 @@
+package conf
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestDiffStorage(t *testing.T, values map[string]string) *FileStorage {
+	t.Helper()
+
+	storage, err := NewFileStorage(filepath.Join(t.TempDir(), "settings.json"), FormatJSON)
+	require.NoError(t, err)
+	for k, v := range values {
+		require.NoError(t, storage.Set(k, v))
+	}
+	return storage
+}
+
+func TestDiff(t *testing.T) {
+	staging := newTestDiffStorage(t, map[string]string{
+		"app.ratio":   "1.0",
+		"app.debug":   "true",
+		"app.timeout": "1m",
+		"app.hosts":   `["a", "b"]`,
+		"app.port":    "8080",
+		"app.legacy":  "x",
+		"other.key":   "ignored",
+	})
+	production := newTestDiffStorage(t, map[string]string{
+		"app.ratio":   "1",
+		"app.debug":   "TRUE",
+		"app.timeout": "60s",
+		"app.hosts":   `["a","b"]`,
+		"app.port":    "9090",
+		"app.new":     "line1\nline2",
+	})
+
+	result, err := Diff(staging, production, "app.")
+	require.NoError(t, err)
+	assert.Equal(t, []SyncChange{{Key: "app.new", NewValue: "line1\nline2"}}, result.Added)
+	assert.Equal(t, []SyncChange{{Key: "app.legacy", OldValue: "x"}}, result.Removed)
+	assert.Equal(t, []SyncChange{{Key: "app.port", OldValue: "8080", NewValue: "9090"}}, result.Changed)
+	assert.False(t, result.Empty())
+
+	var unified strings.Builder
+	require.NoError(t, result.WriteUnified(&unified, "staging", "production"))
+
+	assert.Equal(t, "--- staging\n+++ production\n"+
+		"-app.legacy = x\n"+
+		"+app.new = \"line1\\nline2\"\n"+
+		"-app.port = 8080\n"+
+		"+app.port = 9090\n", unified.String())
+
+	same, err := Diff(staging, staging, "")
+	require.NoError(t, err)
+	assert.True(t, same.Empty())
+
+	_, err = Diff(staging, newMockStorage(), "")
+	assert.ErrorIs(t, err, ErrStorageOperation)
+}
+
+func TestValuesEqual(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"1.0", "1", true},
+		{"1.5e3", "1500.0", true},
+		{"1500", "1500.0", true},
+		{"1e3", "1000", true},
+		{"1e3", "1.0E3", true},
+		{"9007199254740993", "9007199254740992", false},
+		{"9223372036854775807", "9223372036854775806", false},
+		{"0.1", "0.10000000000000001", false},
+		{" 7", "7", false},
+		{"NaN", "NaN", true},
+		{"NaN", "nan", false},
+		{"1", "true", false},
+		{"t", "1", false},
+		{"true", "True", true},
+		{"2024-01-01T00:00:00Z", "2024-01-01T08:00:00+08:00", true},
+		{"90s", "1m30s", true},
+		{`{"a":1,"b":[1,2]}`, `{"b": [1, 2], "a": 1.0}`, true},
+		{`{"a":1}`, `{"a":2}`, false},
+		{"demo", "Demo", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.equal, valuesEqual(tt.a, tt.b), "%q vs %q", tt.a, tt.b)
+		assert.Equal(t, tt.equal, valuesEqual(tt.b, tt.a), "%q vs %q", tt.b, tt.a)
+	}
+}
+
+func TestDiffWith_TextKeys(t *testing.T) {
+	staging := newTestDiffStorage(t, map[string]string{"app.version": "1.10", "app.code": "007", "app.ratio": "1.0"})
+	production := newTestDiffStorage(t, map[string]string{"app.version": "1.1", "app.code": "7", "app.ratio": "1"})
+
+	result, err := Diff(staging, production, "")
+	require.NoError(t, err)
+	assert.True(t, result.Empty())
+
+	// 指定按原文比较的键
+	result, err = DiffWith(staging, production, "", DiffOptions{TextKeys: []string{"app.version", "*.code"}})
+	require.NoError(t, err)
+	assert.Equal(t, []SyncChange{
+		{Key: "app.code", OldValue: "007", NewValue: "7"},
+		{Key: "app.version", OldValue: "1.10", NewValue: "1.1"},
+	}, result.Changed)
+}
+
+func TestSettingManager_DiffRedacts(t *testing.T) {
+	manager := newSettingManager(newTestDiffStorage(t, map[string]string{"db.password": "old"}))
+	manager.MarkSensitive("*.password")
+
+	result, err := manager.Diff(newTestDiffStorage(t, map[string]string{"db.password": "new"}), "")
+	require.NoError(t, err)
+	assert.Equal(t, []SyncChange{{Key: "db.password", OldValue: RedactedValue, NewValue: RedactedValue}}, result.Changed)
+}
@@ -0,0 +1,206 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// 目录嵌套的最大深度，避免符号链接形成环
+const dirStorageMaxDepth = 32
+
+// DirStorage 是只读的目录存储，每个文件对应一个键，文件内容为值，
+// 目录层级映射为点分隔的键（app/db/host → app.db.host）。
+// 兼容 Kubernetes ConfigMap/Secret 挂载：忽略以 ".." 开头的内部目录，
+// 并在 ..data 符号链接原子切换时重新加载，通过 Watch 通知变化的键。
+type DirStorage struct {
+	root     string
+	reload   sync.Mutex
+	mutex    sync.RWMutex
+	values   map[string]string
+	watcher  *fsnotify.Watcher
+	notifier changeNotifier
+	done     chan struct{}
+	closeErr error
+	once     sync.Once
+}
+
+// NewDirStorage 加载目录内容并开始监听变化，使用完毕后应调用 Close
+func NewDirStorage(root string) (*DirStorage, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+
+	ds := &DirStorage{
+		root:    root,
+		watcher: watcher,
+		done:    make(chan struct{}),
+	}
+	if err := ds.Reload(); err != nil {
+		_ = watcher.Close()
+		return nil, err
+	}
+
+	go ds.watchLoop()
+	return ds, nil
+}
+
+func (ds *DirStorage) Get(key string) (string, error) {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+
+	value, ok := ds.values[key]
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (ds *DirStorage) Set(key, value string) error {
+	return ErrReadOnlyStorage
+}
+
+func (ds *DirStorage) Delete(key string) error {
+	return ErrReadOnlyStorage
+}
+
+// Keys 返回以 prefix 开头的所有键
+func (ds *DirStorage) Keys(prefix string) ([]string, error) {
+	ds.mutex.RLock()
+	defer ds.mutex.RUnlock()
+
+	var keys []string
+	for k := range ds.values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Watch 注册变更回调，目录内容变化时以变化的键调用
+func (ds *DirStorage) Watch(fn func(key string)) (cancel func()) {
+	return ds.notifier.Watch(fn)
+}
+
+// Reload 重新扫描目录，并通知新增、修改或删除的键
+func (ds *DirStorage) Reload() error {
+	ds.reload.Lock()
+	defer ds.reload.Unlock()
+
+	values := make(map[string]string)
+	dirs := []string{ds.root}
+	if err := scanSettingDir(ds.root, "", 0, values, &dirs); err != nil {
+		return fmt.Errorf("%w: scan %s: %v", ErrStorageOperation, ds.root, err)
+	}
+
+	// 监听所有真实目录，Kubernetes 的 ..data 切换会触发根目录事件
+	for _, dir := range dirs {
+		_ = ds.watcher.Add(dir)
+	}
+
+	ds.mutex.Lock()
+	old := ds.values
+	ds.values = values
+	ds.mutex.Unlock()
+
+	if old == nil {
+		return nil
+	}
+	for key, value := range values {
+		if oldValue, ok := old[key]; !ok || oldValue != value {
+			ds.notifier.notify(key)
+		}
+	}
+	for key := range old {
+		if _, ok := values[key]; !ok {
+			ds.notifier.notify(key)
+		}
+	}
+	return nil
+}
+
+// Close 停止监听目录变化
+func (ds *DirStorage) Close() error {
+	ds.once.Do(func() {
+		close(ds.done)
+		ds.closeErr = ds.watcher.Close()
+	})
+	return ds.closeErr
+}
+
+func (ds *DirStorage) watchLoop() {
+	for {
+		select {
+		case <-ds.done:
+			return
+		case _, ok := <-ds.watcher.Events:
+			if !ok {
+				return
+			}
+			// 扫描失败时保留上一次成功加载的内容
+			_ = ds.Reload()
+		case _, ok := <-ds.watcher.Errors:
+			if !ok {
+				return
+			}
+		}
+	}
+}
+
+// scanSettingDir 递归读取目录，符号链接会被跟随，以 ".." 开头的条目被忽略
+func scanSettingDir(dir, prefix string, depth int, values map[string]string, dirs *[]string) error {
+	if depth > dirStorageMaxDepth {
+		return fmt.Errorf("directory %s is nested too deeply", dir)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, "..") {
+			continue
+		}
+
+		path := filepath.Join(dir, name)
+		info, err := os.Stat(path)
+		if err != nil {
+			// 悬空的符号链接在切换过程中可能短暂存在
+			continue
+		}
+
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if info.IsDir() {
+			if entry.Type()&os.ModeSymlink == 0 {
+				*dirs = append(*dirs, path)
+			}
+			if err := scanSettingDir(path, key, depth+1, values, dirs); err != nil {
+				return err
+			}
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		// 去掉文件末尾的换行符，便于使用 echo 或编辑器创建的文件
+		value := strings.TrimSuffix(string(data), "\n")
+		values[key] = strings.TrimSuffix(value, "\r")
+	}
+	return nil
+}
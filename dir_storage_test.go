@@ -0,0 +1,109 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfigMapVersion 模拟 kubelet 写入一个新的数据版本并原子切换 ..data 符号链接
+func writeConfigMapVersion(t *testing.T, root, version string, files map[string]string) {
+	t.Helper()
+
+	versionDir := filepath.Join(root, version)
+	for name, content := range files {
+		path := filepath.Join(versionDir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+
+	tmpLink := filepath.Join(root, "..data_tmp")
+	require.NoError(t, os.Symlink(version, tmpLink))
+	require.NoError(t, os.Rename(tmpLink, filepath.Join(root, "..data")))
+}
+
+func TestDirStorage_ConfigMapLayout(t *testing.T) {
+	root := t.TempDir()
+	writeConfigMapVersion(t, root, "..2024_01_01", map[string]string{
+		"app/db/host": "db-1\n",
+		"app.name":    "demo",
+	})
+	// 顶层条目指向 ..data 中的对应文件或目录
+	require.NoError(t, os.Symlink("..data/app", filepath.Join(root, "app")))
+	require.NoError(t, os.Symlink("..data/app.name", filepath.Join(root, "app.name")))
+
+	storage, err := NewDirStorage(root)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	host, err := storage.Get("app.db.host")
+	require.NoError(t, err)
+	assert.Equal(t, "db-1", host)
+
+	keys, err := storage.Keys("")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.db.host", "app.name"}, keys)
+
+	assert.ErrorIs(t, storage.Set("app.name", "x"), ErrReadOnlyStorage)
+	assert.ErrorIs(t, storage.Delete("app.name"), ErrReadOnlyStorage)
+
+	var mu sync.Mutex
+	var changed []string
+	cancel := storage.Watch(func(key string) {
+		mu.Lock()
+		defer mu.Unlock()
+		changed = append(changed, key)
+	})
+	defer cancel()
+
+	manager := newSettingManager(storage)
+	cached, err := getTyped[string](manager, "app.db.host")
+	require.NoError(t, err)
+	assert.Equal(t, "db-1", *cached)
+
+	writeConfigMapVersion(t, root, "..2024_01_02", map[string]string{
+		"app/db/host": "db-2\n",
+		"app.name":    "demo",
+	})
+
+	assert.Eventually(t, func() bool {
+		value, err := getTyped[string](manager, "app.db.host")
+		return err == nil && *value == "db-2"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	assert.Contains(t, changed, "app.db.host")
+	assert.NotContains(t, changed, "app.name")
+	mu.Unlock()
+}
+
+func TestDirStorage_PlainDirectory(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "app", "db"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app", "db", "port"), []byte("5432"), 0o644))
+
+	storage, err := NewDirStorage(root)
+	require.NoError(t, err)
+	defer storage.Close()
+
+	port, err := storage.Get("app.db.port")
+	require.NoError(t, err)
+	assert.Equal(t, "5432", port)
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "app", "db", "host"), []byte("localhost"), 0o644))
+	assert.Eventually(t, func() bool {
+		host, err := storage.Get("app.db.host")
+		return err == nil && host == "localhost"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.Remove(filepath.Join(root, "app", "db", "port")))
+	assert.Eventually(t, func() bool {
+		_, err := storage.Get("app.db.port")
+		return err == ErrKeyNotFound
+	}, 5*time.Second, 10*time.Millisecond)
+}
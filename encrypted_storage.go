@@ -0,0 +1,347 @@
+package conf
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 加密值的前缀，格式为 "enc:v1:<keyID>:<base64(nonce+密文)>"
+const encryptedPrefix = "enc:v1:"
+
+// KeyProvider 提供 AES 密钥（16、24 或 32 字节），新值使用当前密钥加密，
+// 旧密钥用于解密轮换前写入的值
+type KeyProvider interface {
+	// CurrentKey 返回当前密钥及其 ID，ID 不能包含 ":"
+	CurrentKey() (id string, key []byte, err error)
+	// Key 按 ID 返回密钥
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeyProvider 使用固定的密钥集合
+type StaticKeyProvider struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewStaticKeyProvider 创建静态密钥提供者，current 为当前密钥的 ID，keys 中可包含轮换前的旧密钥
+func NewStaticKeyProvider(current string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("%w: current key %q not found", ErrStorageOperation, current)
+	}
+	for id, key := range keys {
+		if err := validateKeyID(id); err != nil {
+			return nil, err
+		}
+		if err := validateAESKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return &StaticKeyProvider{current: current, keys: keys}, nil
+}
+
+func (p *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	return p.current, p.keys[p.current], nil
+}
+
+func (p *StaticKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrDecryption, id)
+	}
+	return key, nil
+}
+
+// NewKeyFileProvider 从密钥文件创建密钥提供者。文件每行为 "<id> <base64 密钥>"，
+// 空行和以 "#" 开头的行被忽略，最后一行为当前密钥，轮换时在末尾追加新密钥即可。
+func NewKeyFileProvider(path string) (*StaticKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+
+	keys := make(map[string][]byte)
+	var current string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%w: %s:%d: expected \"<id> <base64 key>\"", ErrStorageOperation, path, line)
+		}
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s:%d: %v", ErrStorageOperation, path, line, err)
+		}
+		keys[fields[0]] = key
+		current = fields[0]
+	}
+	if current == "" {
+		return nil, fmt.Errorf("%w: %s: no keys", ErrStorageOperation, path)
+	}
+	return NewStaticKeyProvider(current, keys)
+}
+
+// KeyWrapper 使用主密钥（通常托管在 KMS 中）加密和解密数据密钥
+type KeyWrapper interface {
+	WrapKey(plaintext []byte) ([]byte, error)
+	UnwrapKey(ciphertext []byte) ([]byte, error)
+}
+
+// EnvelopeKeyProvider 实现信封加密：创建时生成随机的数据密钥，由 KeyWrapper 加密后
+// 作为密钥 ID 写入每个加密值中，解密时再交给 KeyWrapper 还原，主密钥不离开 KMS。
+// 每次创建都会生成新的数据密钥，配合 EncryptedStorage.Rotate 即可完成轮换。
+type EnvelopeKeyProvider struct {
+	wrapper KeyWrapper
+	current string
+	mutex   sync.Mutex
+	keys    map[string][]byte
+}
+
+// NewEnvelopeKeyProvider 生成新的 256 位数据密钥并用 wrapper 加密
+func NewEnvelopeKeyProvider(wrapper KeyWrapper) (*EnvelopeKeyProvider, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	wrapped, err := wrapper.WrapKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrap data key: %v", ErrStorageOperation, err)
+	}
+
+	id := base64.RawURLEncoding.EncodeToString(wrapped)
+	return &EnvelopeKeyProvider{
+		wrapper: wrapper,
+		current: id,
+		keys:    map[string][]byte{id: key},
+	}, nil
+}
+
+func (p *EnvelopeKeyProvider) CurrentKey() (string, []byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.current, p.keys[p.current], nil
+}
+
+func (p *EnvelopeKeyProvider) Key(id string) ([]byte, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key, ok := p.keys[id]; ok {
+		return key, nil
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid data key: %v", ErrDecryption, err)
+	}
+	key, err := p.wrapper.UnwrapKey(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unwrap data key: %v", ErrDecryption, err)
+	}
+	if err := validateAESKey(key); err != nil {
+		return nil, err
+	}
+	p.keys[id] = key
+	return key, nil
+}
+
+func validateKeyID(id string) error {
+	if id == "" || strings.Contains(id, ":") {
+		return fmt.Errorf("%w: invalid key id %q", ErrStorageOperation, id)
+	}
+	return nil
+}
+
+func validateAESKey(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	default:
+		return fmt.Errorf("%w: invalid AES key length %d", ErrStorageOperation, len(key))
+	}
+}
+
+// EncryptedStorage 包装存储，在写入前使用 AES-GCM 加密匹配模式的键的值，读取时解密。
+// 值以配置键作为附加数据加密，无法将密文复制到其他键下使用。
+// 匹配模式的键读到没有密文前缀的值时返回 ErrDecryption，防止直接写入底层存储的明文
+// 替换加密的值；不匹配模式的键读到密文时仍会解密。已有的明文可以通过 Rotate 加密。
+type EncryptedStorage struct {
+	storage  SettingStorage
+	provider KeyProvider
+	patterns []string
+}
+
+// NewEncryptedStorage 创建加密存储，patterns 为需要加密的键模式（语法同 matchKey），
+// 为空时加密所有键
+func NewEncryptedStorage(storage SettingStorage, provider KeyProvider, patterns ...string) *EncryptedStorage {
+	return &EncryptedStorage{storage: storage, provider: provider, patterns: patterns}
+}
+
+// shouldEncrypt 判断键是否需要加密
+func (es *EncryptedStorage) shouldEncrypt(key string) bool {
+	return len(es.patterns) == 0 || matchAnyKey(es.patterns, key)
+}
+
+func (es *EncryptedStorage) Get(key string) (string, error) {
+	value, err := es.storage.Get(key)
+	if err != nil {
+		return "", err
+	}
+	return es.decrypt(key, value)
+}
+
+func (es *EncryptedStorage) Set(key, value string) error {
+	if es.shouldEncrypt(key) {
+		encrypted, err := es.encrypt(key, value)
+		if err != nil {
+			return err
+		}
+		value = encrypted
+	}
+	return es.storage.Set(key, value)
+}
+
+func (es *EncryptedStorage) Delete(key string) error {
+	return es.storage.Delete(key)
+}
+
+// Keys 返回以 prefix 开头的所有键，底层存储不支持列举时返回错误
+func (es *EncryptedStorage) Keys(prefix string) ([]string, error) {
+	lister, ok := es.storage.(SettingLister)
+	if !ok {
+		return nil, fmt.Errorf("%w: storage does not support listing", ErrStorageOperation)
+	}
+	return lister.Keys(prefix)
+}
+
+// Watch 转发底层存储的变更通知
+func (es *EncryptedStorage) Watch(fn func(key string)) (cancel func()) {
+	if watcher, ok := es.storage.(SettingWatcher); ok {
+		return watcher.Watch(fn)
+	}
+	return func() {}
+}
+
+// Rotate 使用当前密钥重新加密所有需要加密的值：以旧密钥加密的值和匹配模式但仍为明文的值。
+// 底层存储需要实现 SettingLister，返回重新写入的键的数量。
+func (es *EncryptedStorage) Rotate() (int, error) {
+	keys, err := es.Keys("")
+	if err != nil {
+		return 0, err
+	}
+	current, _, err := es.provider.CurrentKey()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+
+	rotated := 0
+	for _, key := range keys {
+		stored, err := es.storage.Get(key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return rotated, err
+		}
+
+		id, _, encrypted := parseEncrypted(stored)
+		if encrypted && id == current {
+			continue
+		}
+		if !encrypted && !es.shouldEncrypt(key) {
+			continue
+		}
+
+		value := stored
+		if encrypted {
+			if value, err = es.decrypt(key, stored); err != nil {
+				return rotated, err
+			}
+		}
+		if err := es.Set(key, value); err != nil {
+			return rotated, err
+		}
+		rotated++
+	}
+	return rotated, nil
+}
+
+func (es *EncryptedStorage) encrypt(key, value string) (string, error) {
+	id, secret, err := es.provider.CurrentKey()
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(key))
+	return encryptedPrefix + id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt 解密存储中的值。未加密的值只有在键不需要加密时才原样返回
+func (es *EncryptedStorage) decrypt(key, stored string) (string, error) {
+	id, payload, ok := parseEncrypted(stored)
+	if !ok {
+		if es.shouldEncrypt(key) {
+			return "", fmt.Errorf("%w: %s: value is not encrypted", ErrDecryption, key)
+		}
+		return stored, nil
+	}
+
+	secret, err := es.provider.Key(id)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := newGCM(secret)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("%w: %s: malformed ciphertext", ErrDecryption, key)
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(key))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s: %v", ErrDecryption, key, err)
+	}
+	return string(plaintext), nil
+}
+
+// parseEncrypted 解析加密值，返回密钥 ID 和 base64 密文
+func parseEncrypted(stored string) (id, payload string, ok bool) {
+	rest, found := strings.CutPrefix(stored, encryptedPrefix)
+	if !found {
+		return "", "", false
+	}
+	return strings.Cut(rest, ":")
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return gcm, nil
+}
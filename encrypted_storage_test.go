@@ -0,0 +1,155 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAESKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+// xorWrapper 是测试用的 KeyWrapper，模拟 KMS 主密钥
+type xorWrapper struct{ mask byte }
+
+func (w xorWrapper) WrapKey(plaintext []byte) ([]byte, error) {
+	out := make([]byte, len(plaintext))
+	for i, b := range plaintext {
+		out[i] = b ^ w.mask
+	}
+	return out, nil
+}
+
+func (w xorWrapper) UnwrapKey(ciphertext []byte) ([]byte, error) {
+	return w.WrapKey(ciphertext)
+}
+
+func TestEncryptedStorage_Patterns(t *testing.T) {
+	backend := newMockStorage()
+	provider, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": testAESKey(1)})
+	require.NoError(t, err)
+	storage := NewEncryptedStorage(backend, provider, "*.password", "*.token")
+
+	require.NoError(t, storage.Set("db.password", "s3cret"))
+	require.NoError(t, storage.Set("db.host", "localhost"))
+
+	assert.True(t, strings.HasPrefix(backend.data["db.password"], "enc:v1:k1:"))
+	assert.NotContains(t, backend.data["db.password"], "s3cret")
+	assert.Equal(t, "localhost", backend.data["db.host"])
+
+	value, err := storage.Get("db.password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", value)
+
+	// 密文与键绑定，不能复制到其他键下
+	backend.data["api.token"] = backend.data["db.password"]
+	_, err = storage.Get("api.token")
+	assert.ErrorIs(t, err, ErrDecryption)
+
+	// 直接写入底层存储的明文不能替换加密的值
+	backend.data["db.password"] = "plain"
+	_, err = storage.Get("db.password")
+	assert.ErrorIs(t, err, ErrDecryption)
+	require.NoError(t, storage.Set("db.password", "s3cret"))
+
+	// 通过管理器读取
+	manager := newSettingManager(storage)
+	password, err := getTyped[string](manager, "db.password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", *password)
+}
+
+func TestEncryptedStorage_Rotate(t *testing.T) {
+	backend, err := NewFileStorage(filepath.Join(t.TempDir(), "settings.json"), FormatJSON)
+	require.NoError(t, err)
+	require.NoError(t, backend.Set("legacy.password", "plain"))
+
+	oldProvider, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": testAESKey(1)})
+	require.NoError(t, err)
+	require.NoError(t, NewEncryptedStorage(backend, oldProvider, "*.password").Set("db.password", "s3cret"))
+	require.NoError(t, backend.Set("db.host", "localhost"))
+
+	newProvider, err := NewStaticKeyProvider("k2", map[string][]byte{"k1": testAESKey(1), "k2": testAESKey(2)})
+	require.NoError(t, err)
+	storage := NewEncryptedStorage(backend, newProvider, "*.password")
+
+	rotated, err := storage.Rotate()
+	require.NoError(t, err)
+	assert.Equal(t, 2, rotated)
+
+	for key, want := range map[string]string{"db.password": "s3cret", "legacy.password": "plain"} {
+		stored, err := backend.Get(key)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(stored, "enc:v1:k2:"), key)
+
+		value, err := storage.Get(key)
+		require.NoError(t, err)
+		assert.Equal(t, want, value)
+	}
+
+	rotated, err = storage.Rotate()
+	require.NoError(t, err)
+	assert.Zero(t, rotated)
+}
+
+func TestKeyFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys")
+	content := "# settings keys\n" +
+		"k1 " + base64.StdEncoding.EncodeToString(testAESKey(1)) + "\n" +
+		"k2 " + base64.StdEncoding.EncodeToString(testAESKey(2)) + "\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	provider, err := NewKeyFileProvider(path)
+	require.NoError(t, err)
+	id, key, err := provider.CurrentKey()
+	require.NoError(t, err)
+	assert.Equal(t, "k2", id)
+	assert.Equal(t, testAESKey(2), key)
+
+	require.NoError(t, os.WriteFile(path, []byte("k1 short\n"), 0o600))
+	_, err = NewKeyFileProvider(path)
+	assert.ErrorIs(t, err, ErrStorageOperation)
+}
+
+func TestEnvelopeKeyProvider(t *testing.T) {
+	backend := newMockStorage()
+	provider, err := NewEnvelopeKeyProvider(xorWrapper{mask: 0x5a})
+	require.NoError(t, err)
+	require.NoError(t, NewEncryptedStorage(backend, provider).Set("db.password", "s3cret"))
+
+	// 另一个进程使用同一个主密钥即可解密
+	other, err := NewEnvelopeKeyProvider(xorWrapper{mask: 0x5a})
+	require.NoError(t, err)
+	value, err := NewEncryptedStorage(backend, other).Get("db.password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", value)
+
+	// 并发加密和解密
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			storage := NewEncryptedStorage(backend, other)
+			key := fmt.Sprintf("worker%d.password", i)
+			assert.NoError(t, storage.Set(key, "s3cret"))
+			_, err := storage.Get("db.password")
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	wrong, err := NewEnvelopeKeyProvider(xorWrapper{mask: 0x11})
+	require.NoError(t, err)
+	_, err = NewEncryptedStorage(backend, wrong).Get("db.password")
+	assert.ErrorIs(t, err, ErrDecryption)
+}
@@ -0,0 +1,163 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/subosito/gotenv"
+)
+
+// EnvCase 控制配置键转换为环境变量名时的大小写
+type EnvCase int
+
+const (
+	// EnvUpper 转换为大写（默认），app.db.host → APP_DB_HOST
+	EnvUpper EnvCase = iota
+	// EnvLower 转换为小写
+	EnvLower
+	// EnvPreserve 保持原样
+	EnvPreserve
+)
+
+// EnvOptions 环境变量存储的配置
+type EnvOptions struct {
+	// Prefix 环境变量名前缀，例如 "MYAPP"，为空时不加前缀
+	Prefix string
+	// Separator 分隔符，默认为 "_"，键中的 "." 和 "-" 都会替换为分隔符
+	Separator string
+	// Case 大小写规则
+	Case EnvCase
+	// Writable 允许 Set/Delete 修改当前进程的环境变量，默认只读
+	Writable bool
+	// Files 需要加载的 .env 文件，已存在的环境变量优先于文件中的值
+	Files []string
+}
+
+// EnvStorage 将配置键映射到环境变量，例如前缀为 MYAPP 时 app.db.host 对应 MYAPP_APP_DB_HOST
+type EnvStorage struct {
+	opts    EnvOptions
+	mutex   sync.RWMutex
+	dotenv  map[string]string
+	replace *strings.Replacer
+}
+
+// NewEnvStorage 创建环境变量存储，并加载配置中的 .env 文件
+func NewEnvStorage(opts EnvOptions) (*EnvStorage, error) {
+	if opts.Separator == "" {
+		opts.Separator = "_"
+	}
+
+	es := &EnvStorage{
+		opts:    opts,
+		dotenv:  make(map[string]string),
+		replace: strings.NewReplacer(".", opts.Separator, "-", opts.Separator),
+	}
+	for _, file := range opts.Files {
+		env, err := gotenv.Read(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: load %s: %v", ErrStorageOperation, file, err)
+		}
+		for k, v := range env {
+			es.dotenv[k] = v
+		}
+	}
+	return es, nil
+}
+
+// EnvName 返回配置键对应的环境变量名
+func (es *EnvStorage) EnvName(key string) string {
+	name := es.replace.Replace(key)
+	if es.opts.Prefix != "" {
+		name = es.opts.Prefix + es.opts.Separator + name
+	}
+
+	switch es.opts.Case {
+	case EnvUpper:
+		return strings.ToUpper(name)
+	case EnvLower:
+		return strings.ToLower(name)
+	default:
+		return name
+	}
+}
+
+func (es *EnvStorage) Get(key string) (string, error) {
+	name := es.EnvName(key)
+	if value, ok := os.LookupEnv(name); ok {
+		return value, nil
+	}
+
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+	if value, ok := es.dotenv[name]; ok {
+		return value, nil
+	}
+	return "", ErrKeyNotFound
+}
+
+func (es *EnvStorage) Set(key, value string) error {
+	if !es.opts.Writable {
+		return ErrReadOnlyStorage
+	}
+	if err := os.Setenv(es.EnvName(key), value); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+func (es *EnvStorage) Delete(key string) error {
+	if !es.opts.Writable {
+		return ErrReadOnlyStorage
+	}
+	name := es.EnvName(key)
+
+	es.mutex.Lock()
+	delete(es.dotenv, name)
+	es.mutex.Unlock()
+
+	if err := os.Unsetenv(name); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+// Keys 返回带有前缀的环境变量对应的键。
+// 由于 "." 和 "-" 都映射为分隔符，还原出的键统一使用 "." 分隔并转换为小写。
+// 未配置 Prefix 时无法区分配置与进程中的其他环境变量（PATH、令牌等），返回错误。
+func (es *EnvStorage) Keys(prefix string) ([]string, error) {
+	if es.opts.Prefix == "" {
+		return nil, fmt.Errorf("%w: listing environment variables requires a prefix", ErrStorageOperation)
+	}
+	namePrefix := es.EnvName("") // 前缀加分隔符
+
+	seen := make(map[string]bool)
+	collect := func(name string) {
+		if !strings.HasPrefix(name, namePrefix) || name == namePrefix {
+			return
+		}
+		key := strings.ToLower(strings.ReplaceAll(strings.TrimPrefix(name, namePrefix), es.opts.Separator, "."))
+		if strings.HasPrefix(key, prefix) {
+			seen[key] = true
+		}
+	}
+
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		collect(name)
+	}
+	es.mutex.RLock()
+	for name := range es.dotenv {
+		collect(name)
+	}
+	es.mutex.RUnlock()
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
@@ -0,0 +1,98 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvStorage_Mapping(t *testing.T) {
+	storage, err := NewEnvStorage(EnvOptions{Prefix: "MYAPP"})
+	require.NoError(t, err)
+	assert.Equal(t, "MYAPP_APP_DB_HOST", storage.EnvName("app.db.host"))
+	assert.Equal(t, "MYAPP_APP_MAX_CONNS", storage.EnvName("app.max-conns"))
+
+	lower, err := NewEnvStorage(EnvOptions{Prefix: "myapp", Separator: "__", Case: EnvLower})
+	require.NoError(t, err)
+	assert.Equal(t, "myapp__app__db__host", lower.EnvName("app.db.host"))
+
+	t.Setenv("MYAPP_APP_DB_HOST", "db.local")
+	t.Setenv("MYAPP_APP_DB_PORT", "5432")
+
+	value, err := storage.Get("app.db.host")
+	require.NoError(t, err)
+	assert.Equal(t, "db.local", value)
+
+	_, err = storage.Get("app.db.user")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	keys, err := storage.Keys("app.db.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.db.host", "app.db.port"}, keys)
+
+	// 没有前缀时不列举整个进程环境
+	unprefixed, err := NewEnvStorage(EnvOptions{})
+	require.NoError(t, err)
+	_, err = unprefixed.Keys("")
+	assert.ErrorIs(t, err, ErrStorageOperation)
+}
+
+func TestEnvStorage_ReadOnly(t *testing.T) {
+	storage, err := NewEnvStorage(EnvOptions{Prefix: "MYAPP"})
+	require.NoError(t, err)
+	assert.ErrorIs(t, storage.Set("app.name", "x"), ErrReadOnlyStorage)
+	assert.ErrorIs(t, storage.Delete("app.name"), ErrReadOnlyStorage)
+
+	t.Setenv("MYAPP_APP_NAME", "")
+	writable, err := NewEnvStorage(EnvOptions{Prefix: "MYAPP", Writable: true})
+	require.NoError(t, err)
+	require.NoError(t, writable.Set("app.name", "demo"))
+	assert.Equal(t, "demo", os.Getenv("MYAPP_APP_NAME"))
+
+	require.NoError(t, writable.Delete("app.name"))
+	_, err = writable.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestEnvStorage_DotEnvFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte("MYAPP_APP_NAME=from-file\nMYAPP_APP_PORT=8080\n"), 0o644))
+	t.Setenv("MYAPP_APP_PORT", "9090")
+
+	storage, err := NewEnvStorage(EnvOptions{Prefix: "MYAPP", Files: []string{path}})
+	require.NoError(t, err)
+
+	name, err := storage.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", name)
+
+	// 进程环境变量优先于 .env 文件
+	port, err := storage.Get("app.port")
+	require.NoError(t, err)
+	assert.Equal(t, "9090", port)
+
+	// .env 文件不会写入进程环境
+	_, ok := os.LookupEnv("MYAPP_APP_NAME")
+	assert.False(t, ok)
+
+	_, err = NewEnvStorage(EnvOptions{Files: []string{filepath.Join(t.TempDir(), "missing.env")}})
+	assert.ErrorIs(t, err, ErrStorageOperation)
+}
+
+func TestEnvStorage_AsSource(t *testing.T) {
+	t.Setenv("MYAPP_APP_PORT", "9090")
+	env, err := NewEnvStorage(EnvOptions{Prefix: "MYAPP"})
+	require.NoError(t, err)
+
+	storage := newMockStorage()
+	manager := newSettingManager(storage)
+	manager.SetSources(NewStorageSource("env", env), NewStorageSource("database", storage))
+	require.NoError(t, manager.Set("app.port", 8080))
+
+	port, err := getTyped[int](manager, "app.port")
+	require.NoError(t, err)
+	assert.Equal(t, 9090, *port)
+}
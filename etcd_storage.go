@@ -0,0 +1,249 @@
+package conf
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// EtcdOptions etcd 存储的配置
+type EtcdOptions struct {
+	// Prefix 所有配置键的前缀，默认为 "conf/"
+	Prefix string
+	// Timeout 单次请求的超时时间，默认为 5 秒
+	Timeout time.Duration
+}
+
+// etcd watch 通道关闭后重新建立之前的等待时间
+const etcdRewatchDelay = 200 * time.Millisecond
+
+// EtcdStorage 基于 etcd v3 API 的存储，使用修订号（ModRevision）作为版本号支持 CAS，
+// 并将 watch 事件转换为变更通知，使管理器缓存失效。
+type EtcdStorage struct {
+	client   *clientv3.Client
+	opts     EtcdOptions
+	notifier changeNotifier
+
+	watchOnce  sync.Once
+	watchMutex sync.Mutex
+	watcher    clientv3.Watcher
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+}
+
+// NewEtcdStorage 使用已有的 etcd 客户端创建存储
+func NewEtcdStorage(client *clientv3.Client, opts EtcdOptions) *EtcdStorage {
+	if opts.Prefix == "" {
+		opts.Prefix = "conf/"
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EtcdStorage{client: client, opts: opts, ctx: ctx, cancel: cancel, done: make(chan struct{})}
+}
+
+func (es *EtcdStorage) requestContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(es.ctx, es.opts.Timeout)
+}
+
+func (es *EtcdStorage) Get(key string) (string, error) {
+	value, _, err := es.GetVersion(key)
+	return value, err
+}
+
+// GetVersion 返回键的值及其修订号
+func (es *EtcdStorage) GetVersion(key string) (string, int64, error) {
+	ctx, cancel := es.requestContext()
+	defer cancel()
+
+	resp, err := es.client.Get(ctx, es.opts.Prefix+key)
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if len(resp.Kvs) == 0 {
+		return "", 0, ErrKeyNotFound
+	}
+	kv := resp.Kvs[0]
+	return string(kv.Value), kv.ModRevision, nil
+}
+
+func (es *EtcdStorage) Set(key, value string) error {
+	ctx, cancel := es.requestContext()
+	defer cancel()
+
+	if _, err := es.client.Put(ctx, es.opts.Prefix+key, value); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+// CompareAndSet 仅当键的修订号等于 version 时写入，version 为 0 表示键必须不存在
+func (es *EtcdStorage) CompareAndSet(key, value string, version int64) (int64, error) {
+	ctx, cancel := es.requestContext()
+	defer cancel()
+
+	name := es.opts.Prefix + key
+	resp, err := es.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(name), "=", version)).
+		Then(clientv3.OpPut(name, value)).
+		Commit()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if !resp.Succeeded {
+		return 0, ErrVersionConflict
+	}
+	return resp.Header.Revision, nil
+}
+
+// CompareAndDelete 仅当键的修订号等于 version 时删除
+func (es *EtcdStorage) CompareAndDelete(key string, version int64) error {
+	if version <= 0 {
+		return ErrVersionConflict
+	}
+	ctx, cancel := es.requestContext()
+	defer cancel()
+
+	name := es.opts.Prefix + key
+	resp, err := es.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(name), "=", version)).
+		Then(clientv3.OpDelete(name)).
+		Commit()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if !resp.Succeeded {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (es *EtcdStorage) Delete(key string) error {
+	ctx, cancel := es.requestContext()
+	defer cancel()
+
+	if _, err := es.client.Delete(ctx, es.opts.Prefix+key); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+// Keys 返回以 prefix 开头的所有键
+func (es *EtcdStorage) Keys(prefix string) ([]string, error) {
+	ctx, cancel := es.requestContext()
+	defer cancel()
+
+	resp, err := es.client.Get(ctx, es.opts.Prefix+prefix, clientv3.WithPrefix(), clientv3.WithKeysOnly(),
+		clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+
+	keys := make([]string, 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		keys = append(keys, strings.TrimPrefix(string(kv.Key), es.opts.Prefix))
+	}
+	return keys, nil
+}
+
+// Watch 注册变更回调，第一次调用时开始 watch 前缀下的所有键
+func (es *EtcdStorage) Watch(fn func(key string)) (cancel func()) {
+	cancel = es.notifier.Watch(fn)
+	es.watchOnce.Do(es.startWatch)
+	return cancel
+}
+
+func (es *EtcdStorage) startWatch() {
+	created, late := make(chan struct{}), make(chan struct{})
+	var createdOnce sync.Once
+	markCreated := func() {
+		createdOnce.Do(func() {
+			close(created)
+			select {
+			case <-late:
+				// 超时后才建立，期间的变更可能已经错过，让所有缓存失效
+				es.notifier.notify("")
+			default:
+			}
+		})
+	}
+
+	go func() {
+		defer close(es.done)
+		defer markCreated()
+		// watch 通道在压缩、失去 leader 等情况下会关闭，此时重新建立，直到存储被关闭
+		var rev int64
+		for {
+			rev = es.runWatch(rev, markCreated)
+			select {
+			case <-es.ctx.Done():
+				return
+			case <-time.After(etcdRewatchDelay):
+			}
+			if rev == 0 {
+				// 无法从上次的修订号继续，期间的变更可能已经错过，让所有缓存失效
+				es.notifier.notify("")
+			}
+		}
+	}()
+
+	// 等待 watch 建立，避免错过紧随其后的变更；etcd 不可达时最多等待 Timeout
+	timer := time.NewTimer(es.opts.Timeout)
+	defer timer.Stop()
+	select {
+	case <-created:
+	case <-timer.C:
+		close(late)
+	}
+}
+
+// runWatch 从修订号 rev 之后（rev 为 0 时从当前）开始 watch，直到通道关闭，
+// 返回最后处理的修订号，无法继续时返回 0
+func (es *EtcdStorage) runWatch(rev int64, created func()) int64 {
+	watcher := clientv3.NewWatcher(es.client)
+	defer watcher.Close()
+	es.watchMutex.Lock()
+	es.watcher = watcher
+	es.watchMutex.Unlock()
+
+	opts := []clientv3.OpOption{clientv3.WithPrefix(), clientv3.WithCreatedNotify()}
+	if rev > 0 {
+		opts = append(opts, clientv3.WithRev(rev+1))
+	}
+	// etcd 不可达时 Watch 会一直阻塞到连接建立，因此在后台运行
+	for resp := range watcher.Watch(clientv3.WithRequireLeader(es.ctx), es.opts.Prefix, opts...) {
+		created()
+		if resp.CompactRevision > 0 {
+			// 需要的修订已被压缩，事件已丢失
+			es.notifier.notify("")
+			rev = 0
+			continue
+		}
+		if err := resp.Err(); err != nil {
+			es.notifier.notify("")
+			continue
+		}
+		for _, ev := range resp.Events {
+			es.notifier.notify(strings.TrimPrefix(string(ev.Kv.Key), es.opts.Prefix))
+		}
+		if resp.Header.Revision > rev {
+			rev = resp.Header.Revision
+		}
+	}
+	return rev
+}
+
+// Close 停止 watch 并取消未完成的请求，不会关闭传入的客户端
+func (es *EtcdStorage) Close() error {
+	es.cancel()
+	// 如果从未开始 watch，在这里关闭 done
+	es.watchOnce.Do(func() { close(es.done) })
+	<-es.done
+	return nil
+}
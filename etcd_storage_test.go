@@ -0,0 +1,177 @@
+package conf
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.etcd.io/etcd/server/v3/embed"
+)
+
+// newTestEtcdClient 启动进程内的 etcd 服务并返回客户端
+func newTestEtcdClient(t *testing.T) *clientv3.Client {
+	t.Helper()
+
+	cfg := embed.NewConfig()
+	cfg.Dir = t.TempDir()
+	cfg.LogLevel = "error"
+	clientURL, _ := url.Parse("http://127.0.0.1:0")
+	peerURL, _ := url.Parse("http://127.0.0.1:0")
+	cfg.ListenClientUrls = []url.URL{*clientURL}
+	cfg.AdvertiseClientUrls = []url.URL{*clientURL}
+	cfg.ListenPeerUrls = []url.URL{*peerURL}
+	cfg.AdvertisePeerUrls = []url.URL{*peerURL}
+	cfg.InitialCluster = cfg.Name + "=" + peerURL.String()
+
+	server, err := embed.StartEtcd(cfg)
+	require.NoError(t, err)
+	t.Cleanup(server.Close)
+
+	select {
+	case <-server.Server.ReadyNotify():
+	case <-time.After(30 * time.Second):
+		t.Fatal("etcd server did not become ready")
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:   []string{server.Clients[0].Addr().String()},
+		DialTimeout: 5 * time.Second,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestEtcdStorage(t *testing.T) {
+	client := newTestEtcdClient(t)
+
+	t.Run("basic operations", func(t *testing.T) {
+		storage := NewEtcdStorage(client, EtcdOptions{Prefix: "basic/"})
+		defer storage.Close()
+
+		_, err := storage.Get("app.name")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+
+		require.NoError(t, storage.Set("app.name", "demo"))
+		require.NoError(t, storage.Set("app.db.host", "localhost"))
+		require.NoError(t, storage.Set("app.db.port", "5432"))
+
+		value, err := storage.Get("app.name")
+		require.NoError(t, err)
+		assert.Equal(t, "demo", value)
+
+		keys, err := storage.Keys("app.db.")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"app.db.host", "app.db.port"}, keys)
+
+		require.NoError(t, storage.Delete("app.name"))
+		_, err = storage.Get("app.name")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("compare and swap", func(t *testing.T) {
+		storage := NewEtcdStorage(client, EtcdOptions{Prefix: "cas/"})
+		defer storage.Close()
+
+		rev, err := storage.CompareAndSet("counter", "1", 0)
+		require.NoError(t, err)
+
+		// 键已存在时，版本 0 的写入失败
+		_, err = storage.CompareAndSet("counter", "x", 0)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+
+		value, version, err := storage.GetVersion("counter")
+		require.NoError(t, err)
+		assert.Equal(t, "1", value)
+		assert.Equal(t, rev, version)
+
+		_, err = storage.CompareAndSet("counter", "2", version)
+		require.NoError(t, err)
+
+		// 使用过期的版本号写入失败
+		_, err = storage.CompareAndSet("counter", "3", version)
+		assert.ErrorIs(t, err, ErrVersionConflict)
+
+		_, version, err = storage.GetVersion("counter")
+		require.NoError(t, err)
+		assert.ErrorIs(t, storage.CompareAndDelete("counter", version-1), ErrVersionConflict)
+		require.NoError(t, storage.CompareAndDelete("counter", version))
+		_, err = storage.Get("counter")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("watch invalidates cache", func(t *testing.T) {
+		writer := NewEtcdStorage(client, EtcdOptions{Prefix: "watch/"})
+		reader := NewEtcdStorage(client, EtcdOptions{Prefix: "watch/"})
+		defer writer.Close()
+		defer reader.Close()
+
+		manager := newSettingManager(reader)
+		require.NoError(t, writer.Set("app.port", "8080"))
+
+		port, err := getTyped[int](manager, "app.port")
+		require.NoError(t, err)
+		assert.Equal(t, 8080, *port)
+
+		require.NoError(t, writer.Set("app.port", "9090"))
+		assert.Eventually(t, func() bool {
+			port, err := getTyped[int](manager, "app.port")
+			return err == nil && *port == 9090
+		}, 5*time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestEtcdStorage_Rewatch(t *testing.T) {
+	client := newTestEtcdClient(t)
+	writer := NewEtcdStorage(client, EtcdOptions{Prefix: "rewatch/"})
+	reader := NewEtcdStorage(client, EtcdOptions{Prefix: "rewatch/"})
+	defer writer.Close()
+	defer reader.Close()
+
+	manager := newSettingManager(reader)
+	require.NoError(t, writer.Set("app.port", "8080"))
+	_, err := manager.Get("app.port")
+	require.NoError(t, err)
+
+	// 关闭当前的 watch，模拟通道因压缩或失去 leader 而关闭
+	reader.watchMutex.Lock()
+	require.NoError(t, reader.watcher.Close())
+	reader.watchMutex.Unlock()
+
+	// 通道关闭期间的变更在重新建立后仍会使缓存失效
+	require.NoError(t, writer.Set("app.port", "9090"))
+	assert.Eventually(t, func() bool {
+		value, err := manager.Get("app.port")
+		return err == nil && value == "9090"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, writer.Set("app.port", "7070"))
+	assert.Eventually(t, func() bool {
+		value, err := manager.Get("app.port")
+		return err == nil && value == "7070"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestEtcdStorage_WatchUnreachable(t *testing.T) {
+	client, err := clientv3.New(clientv3.Config{Endpoints: []string{"127.0.0.1:1"}})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	storage := NewEtcdStorage(client, EtcdOptions{Timeout: 100 * time.Millisecond})
+	defer storage.Close()
+
+	// etcd 不可达时创建管理器不应阻塞
+	done := make(chan struct{})
+	go func() {
+		newSettingManager(storage)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("creating a manager blocked on an unreachable etcd")
+	}
+}
@@ -0,0 +1,249 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ImportPolicy 控制导入时如何处理存储层中已有的键
+type ImportPolicy int
+
+const (
+	// ImportMerge 写入新增和变化的键，保留导入数据中没有的键
+	ImportMerge ImportPolicy = iota
+	// ImportReplace 写入新增和变化的键，并删除导入数据中没有的键
+	ImportReplace
+	// ImportDryRun 只计算 ImportReplace 会产生的差异，不写入存储层
+	ImportDryRun
+)
+
+// String 返回策略名称
+func (p ImportPolicy) String() string {
+	switch p {
+	case ImportMerge:
+		return "merge"
+	case ImportReplace:
+		return "replace"
+	case ImportDryRun:
+		return "dry-run"
+	default:
+		return fmt.Sprintf("ImportPolicy(%d)", int(p))
+	}
+}
+
+// ImportResult 汇总一次导入的结果，敏感键的值会被隐藏
+type ImportResult struct {
+	// Added 存储层中原本不存在的键
+	Added []SyncChange `json:"added"`
+	// Changed 存储层中值不同的键
+	Changed []SyncChange `json:"changed"`
+	// Removed 导入数据中没有的键；只有 ImportReplace 会删除这些键
+	Removed []string `json:"removed"`
+	// Unchanged 值相同的键，以及导入数据中值为 RedactedValue 且存储层中已有值而保留的键
+	Unchanged []string `json:"unchanged"`
+	// Skipped 导入数据中值为 RedactedValue 但存储层中没有值的键，这些键没有被写入
+	Skipped []string `json:"skipped"`
+	// Applied 是否实际写入了存储层
+	Applied bool `json:"applied"`
+}
+
+// exportValues 读取存储层中的所有配置
+func (sm *SettingManager) exportValues() (map[string]string, error) {
+	lister, ok := sm.storage.(SettingLister)
+	if !ok {
+		return nil, fmt.Errorf("%w: storage does not support listing", ErrStorageOperation)
+	}
+	keys, err := lister.Keys("")
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, err := sm.storage.Get(key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// Export 将存储层中的所有配置按格式写入 w，存储层需要实现 SettingLister。
+// 敏感键的值导出为 RedactedValue，密钥引用按原样导出。
+// 同时存在 "a" 和 "a.b" 这类无法展开为嵌套对象的键时，所有键以点分隔的形式导出。
+func (sm *SettingManager) Export(w io.Writer, format Format) error {
+	values, err := sm.exportValues()
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		values[key] = sm.redactString(key, value)
+	}
+
+	data, err := marshalNested(values, nil, format)
+	if errors.Is(err, errKeyConflict) {
+		data, err = marshalFlat(values, format)
+	}
+	if err != nil {
+		return fmt.Errorf("export error: %w", err)
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Import 从 r 读取配置并按策略写入存储层，写入经过管理器，因此会更新缓存和审计记录。
+// 值为 RedactedValue 的键（来自 Export 的敏感键）会被跳过，保留存储层中的值；
+// 存储层中没有值的这类键记录在 ImportResult.Skipped 中。
+// ImportReplace 和 ImportDryRun 需要存储层实现 SettingLister。
+// 存储层实现 SettingTransactor 时所有修改在一个事务中应用。
+func (sm *SettingManager) Import(r io.Reader, format Format, policy ImportPolicy) (*ImportResult, error) {
+	return sm.ImportContext(context.Background(), r, format, policy)
+}
+
+// ImportContext 与 Import 相同，ctx 提供授权检查使用的主体。
+// 设置了授权器时，在写入任何键之前检查所有待写入和待删除键的权限。
+func (sm *SettingManager) ImportContext(ctx context.Context, r io.Reader, format Format, policy ImportPolicy) (*ImportResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("import error: %w", err)
+	}
+	incoming, err := unmarshalNested(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("import error: %w", err)
+	}
+
+	var existing map[string]string
+	if policy == ImportMerge {
+		existing = make(map[string]string)
+		for key := range incoming {
+			value, err := sm.storage.Get(key)
+			if errors.Is(err, ErrKeyNotFound) {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			existing[key] = value
+		}
+	} else if existing, err = sm.exportValues(); err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Applied: policy != ImportDryRun}
+
+	keys := make([]string, 0, len(incoming))
+	for key := range incoming {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var writes []string
+	for _, key := range keys {
+		newValue := incoming[key]
+		oldValue, exists := existing[key]
+		change := SyncChange{
+			Key:      key,
+			OldValue: sm.redactString(key, oldValue),
+			NewValue: sm.redactString(key, newValue),
+		}
+		switch {
+		case newValue == RedactedValue && !exists:
+			result.Skipped = append(result.Skipped, key)
+			continue
+		case newValue == RedactedValue || (exists && oldValue == newValue):
+			result.Unchanged = append(result.Unchanged, key)
+			continue
+		case !exists:
+			change.OldValue = ""
+			result.Added = append(result.Added, change)
+		default:
+			result.Changed = append(result.Changed, change)
+		}
+		writes = append(writes, key)
+	}
+
+	if policy != ImportMerge {
+		for key := range existing {
+			if _, ok := incoming[key]; !ok {
+				result.Removed = append(result.Removed, key)
+			}
+		}
+		sort.Strings(result.Removed)
+	}
+
+	if policy == ImportDryRun {
+		return result, nil
+	}
+	if err := sm.authorizeWrites(ctx, writes, result.Removed); err != nil {
+		return nil, err
+	}
+
+	if err := sm.applyImport(WithReason(ctx, "import"), incoming, writes, result.Removed); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// applyImport 写入导入的键并删除多余的键。存储层实现 SettingTransactor 时所有修改在一个事务中完成，
+// 失败时不会只应用一部分；审计记录在事务开始之前写入。
+func (sm *SettingManager) applyImport(ctx context.Context, values map[string]string, writes, deletes []string) error {
+	transactor, ok := sm.storage.(SettingTransactor)
+	if !ok {
+		for _, key := range writes {
+			if err := sm.set(ctx, key, values[key]); err != nil {
+				return fmt.Errorf("write %s: %w", key, err)
+			}
+		}
+		for _, key := range deletes {
+			if err := sm.delete(ctx, key); err != nil {
+				return fmt.Errorf("delete %s: %w", key, err)
+			}
+		}
+		return nil
+	}
+
+	// 与 sm.set 使用相同的编码
+	encoded := make(map[string]string, len(writes))
+	for _, key := range writes {
+		value, err := sm.encodeValue(key, values[key])
+		if err != nil {
+			return fmt.Errorf("write %s: %w", key, err)
+		}
+		encoded[key] = value
+	}
+	for _, key := range writes {
+		if err := sm.audit(ctx, AuditSet, key, encoded[key]); err != nil {
+			return err
+		}
+	}
+	for _, key := range deletes {
+		if err := sm.audit(ctx, AuditDelete, key, ""); err != nil {
+			return err
+		}
+	}
+
+	err := transactor.Update(func(tx SettingTx) error {
+		for _, key := range writes {
+			if err := tx.Set(key, encoded[key]); err != nil {
+				return fmt.Errorf("write %s: %w", key, err)
+			}
+		}
+		for _, key := range deletes {
+			if err := tx.Delete(key); err != nil {
+				return fmt.Errorf("delete %s: %w", key, err)
+			}
+		}
+		return nil
+	})
+	for _, key := range append(writes, deletes...) {
+		sm.cache.Delete(key)
+	}
+	return err
+}
@@ -0,0 +1,240 @@
+package conf
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestExportManager(t *testing.T) *SettingManager {
+	t.Helper()
+
+	storage, err := NewFileStorage(filepath.Join(t.TempDir(), "settings.json"), FormatJSON)
+	require.NoError(t, err)
+	return newSettingManager(storage)
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatYAML, FormatTOML, FormatDotenv} {
+		t.Run(string(format), func(t *testing.T) {
+			staging := newTestExportManager(t)
+			require.NoError(t, staging.Set("app.name", "demo \"quoted\" $HOME"))
+			require.NoError(t, staging.Set("app.motd", "line1\nline2 \\n"))
+			require.NoError(t, staging.Set("app.db.port", 5432))
+
+			var buf bytes.Buffer
+			require.NoError(t, staging.Export(&buf, format))
+
+			production := newTestExportManager(t)
+			result, err := production.Import(&buf, format, ImportMerge)
+			require.NoError(t, err)
+			assert.Len(t, result.Added, 3)
+			assert.True(t, result.Applied)
+
+			for _, key := range []string{"app.name", "app.motd", "app.db.port"} {
+				want, err := staging.Get(key)
+				require.NoError(t, err)
+				got, err := production.Get(key)
+				require.NoError(t, err)
+				assert.Equal(t, want, got, key)
+			}
+		})
+	}
+}
+
+func TestImport_Policies(t *testing.T) {
+	input := "app.name=demo\napp.port=9090\n"
+
+	manager := newTestExportManager(t)
+	require.NoError(t, manager.Set("app.port", 8080))
+	require.NoError(t, manager.Set("app.legacy", true))
+
+	result, err := manager.Import(strings.NewReader(input), FormatDotenv, ImportDryRun)
+	require.NoError(t, err)
+	assert.False(t, result.Applied)
+	assert.Equal(t, []SyncChange{{Key: "app.name", NewValue: "demo"}}, result.Added)
+	assert.Equal(t, []SyncChange{{Key: "app.port", OldValue: "8080", NewValue: "9090"}}, result.Changed)
+	assert.Equal(t, []string{"app.legacy"}, result.Removed)
+	_, err = manager.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	result, err = manager.Import(strings.NewReader(input), FormatDotenv, ImportMerge)
+	require.NoError(t, err)
+	assert.Empty(t, result.Removed)
+	_, err = manager.Get("app.legacy")
+	assert.NoError(t, err)
+
+	result, err = manager.Import(strings.NewReader(input), FormatDotenv, ImportReplace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.name", "app.port"}, result.Unchanged)
+	assert.Equal(t, []string{"app.legacy"}, result.Removed)
+	_, err = manager.Get("app.legacy")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestExportImport_Sensitive(t *testing.T) {
+	source := newTestExportManager(t)
+	source.MarkSensitive("*.password")
+	require.NoError(t, source.Set("db.password", "hunter2"))
+	require.NoError(t, source.Set("db.host", "localhost"))
+
+	var buf bytes.Buffer
+	require.NoError(t, source.Export(&buf, FormatYAML))
+	assert.NotContains(t, buf.String(), "hunter2")
+
+	target := newTestExportManager(t)
+	require.NoError(t, target.Set("db.password", "production-secret"))
+	result, err := target.Import(&buf, FormatYAML, ImportReplace)
+	require.NoError(t, err)
+	assert.Contains(t, result.Unchanged, "db.password")
+	assert.Empty(t, result.Removed)
+
+	password, err := target.Get("db.password")
+	require.NoError(t, err)
+	assert.Equal(t, "production-secret", password)
+
+	// 目标中没有值的敏感键单独报告，不算作未变化
+	target = newTestExportManager(t)
+	result, err = target.Import(strings.NewReader("db.password=[REDACTED]\ndb.host=localhost\n"), FormatDotenv, ImportMerge)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"db.password"}, result.Skipped)
+	assert.Empty(t, result.Unchanged)
+	_, err = target.Get("db.password")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestExport_ConflictingKeys(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatYAML, FormatTOML} {
+		t.Run(string(format), func(t *testing.T) {
+			source, err := NewBoltStorage(filepath.Join(t.TempDir(), "source.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = source.Close() })
+			staging := newSettingManager(source)
+			require.NoError(t, staging.Set("app", "demo"))
+			require.NoError(t, staging.Set("app.port", 8080))
+
+			// "app" 和 "app.port" 无法展开为嵌套对象，以点分隔的键导出
+			var buf bytes.Buffer
+			require.NoError(t, staging.Export(&buf, format))
+
+			target, err := NewBoltStorage(filepath.Join(t.TempDir(), "target.db"))
+			require.NoError(t, err)
+			t.Cleanup(func() { _ = target.Close() })
+			production := newSettingManager(target)
+			result, err := production.Import(&buf, format, ImportMerge)
+			require.NoError(t, err)
+			assert.Len(t, result.Added, 2)
+
+			value, err := production.Get("app")
+			require.NoError(t, err)
+			assert.Equal(t, "demo", value)
+			value, err = production.Get("app.port")
+			require.NoError(t, err)
+			assert.Equal(t, "8080", value)
+		})
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	values, err := parseDotenv([]byte(`
+# comment
+export APP_NAME=demo # inline comment
+single='it s $raw' # inline comment
+double="a\tb \"c\" \$d"
+empty=
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"APP_NAME": "demo",
+		"single":   "it s $raw",
+		"double":   "a\tb \"c\" $d",
+		"empty":    "",
+	}, values)
+
+	_, err = parseDotenv([]byte(`bad="unterminated`))
+	assert.Error(t, err)
+
+	// 闭合引号之后的内容不会被静默丢弃
+	_, err = parseDotenv([]byte(`single='it''s $raw'`))
+	assert.ErrorContains(t, err, "line 1")
+	_, err = parseDotenv([]byte(`double="a"b`))
+	assert.Error(t, err)
+}
+
+// failingTxStorage 在事务中写入 failKey 时返回错误
+type failingTxStorage struct {
+	*BoltStorage
+	failKey string
+}
+
+func (fs *failingTxStorage) Update(fn func(tx SettingTx) error) error {
+	return fs.BoltStorage.Update(func(tx SettingTx) error {
+		return fn(&failingTx{SettingTx: tx, failKey: fs.failKey})
+	})
+}
+
+type failingTx struct {
+	SettingTx
+	failKey string
+}
+
+func (ft *failingTx) Set(key, value string) error {
+	if key == ft.failKey {
+		return fmt.Errorf("%w: disk full", ErrStorageOperation)
+	}
+	return ft.SettingTx.Set(key, value)
+}
+
+func TestImport_Transactional(t *testing.T) {
+	bolt, err := NewBoltStorage(filepath.Join(t.TempDir(), "settings.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = bolt.Close() })
+	storage := &failingTxStorage{BoltStorage: bolt, failKey: "app.port"}
+
+	manager := newSettingManager(storage)
+	require.NoError(t, manager.Set("app.legacy", "true"))
+	require.NoError(t, manager.Set("app.name", "old"))
+	_, err = manager.Get("app.name")
+	require.NoError(t, err)
+
+	_, err = manager.Import(strings.NewReader("app.name=new\napp.port=9090\n"), FormatDotenv, ImportReplace)
+	require.ErrorIs(t, err, ErrStorageOperation)
+	assert.ErrorContains(t, err, "write app.port")
+
+	// 事务回滚，没有键被修改或删除，缓存中也不会留下未提交的值
+	value, err := manager.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "old", value)
+	_, err = manager.Get("app.legacy")
+	assert.NoError(t, err)
+
+	storage.failKey = ""
+	result, err := manager.Import(strings.NewReader("app.name=new\napp.port=9090\n"), FormatDotenv, ImportReplace)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.legacy"}, result.Removed)
+	value, err = manager.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "new", value)
+	_, err = manager.Get("app.legacy")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestImport_PreservesCause(t *testing.T) {
+	manager := newSettingManager(unavailableStorage{})
+	_, err := manager.Import(strings.NewReader("app.name=demo\n"), FormatDotenv, ImportMerge)
+	assert.ErrorIs(t, err, ErrStorageOperation)
+
+	sink, err := NewJSONLAuditSink(filepath.Join(t.TempDir(), "audit.jsonl"))
+	require.NoError(t, err)
+	require.NoError(t, sink.Close())
+	manager = newSettingManager(newMockStorage())
+	manager.SetAuditSink(sink)
+	_, err = manager.Import(strings.NewReader("app.name=demo\n"), FormatDotenv, ImportMerge)
+	assert.ErrorIs(t, err, ErrAudit)
+	assert.ErrorContains(t, err, "write app.name")
+}
@@ -0,0 +1,219 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 文件锁相关参数
+const (
+	fileLockRetryInterval = 10 * time.Millisecond
+	fileLockTimeout       = 5 * time.Second
+	fileLockStaleAfter    = 30 * time.Second
+)
+
+// FileStorage 将所有配置保存在单个 JSON、YAML 或 TOML 文件中，
+// 点分隔的键映射为嵌套对象。写入通过临时文件 + rename 原子完成，
+// 并使用锁文件避免多个进程同时修改。
+type FileStorage struct {
+	path   string
+	format Format
+	mutex  sync.Mutex
+}
+
+// NewFileStorage 创建文件存储，文件不存在时会在第一次写入时创建
+func NewFileStorage(path string, format Format) (*FileStorage, error) {
+	if _, err := ParseFormat(string(format)); err != nil {
+		return nil, err
+	}
+	return &FileStorage{path: path, format: format}, nil
+}
+
+func (fs *FileStorage) Get(key string) (string, error) {
+	values, _, err := fs.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := values[key]
+	if !ok {
+		return "", ErrKeyNotFound
+	}
+	return value, nil
+}
+
+func (fs *FileStorage) Set(key, value string) error {
+	return fs.update(func(values map[string]string) bool {
+		values[key] = value
+		return true
+	})
+}
+
+func (fs *FileStorage) Delete(key string) error {
+	return fs.update(func(values map[string]string) bool {
+		if _, ok := values[key]; !ok {
+			return false
+		}
+		delete(values, key)
+		return true
+	})
+}
+
+// Keys 返回以 prefix 开头的所有键
+func (fs *FileStorage) Keys(prefix string) ([]string, error) {
+	values, _, err := fs.load()
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	for k := range values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// load 读取并展开配置文件，同时返回原始叶子值用于写回时保留类型，文件不存在时返回空集合
+func (fs *FileStorage) load() (map[string]string, map[string]any, error) {
+	data, err := os.ReadFile(fs.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]string), nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return make(map[string]string), nil, nil
+	}
+
+	values, leaves, err := unmarshalLeaves(data, fs.format)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: parse %s: %v", ErrStorageOperation, fs.path, err)
+	}
+	return values, leaves, nil
+}
+
+// update 在持有锁的情况下读取、修改并写回配置文件，fn 返回 false 时不写回
+func (fs *FileStorage) update(fn func(values map[string]string) bool) error {
+	fs.mutex.Lock()
+	defer fs.mutex.Unlock()
+
+	unlock, err := acquireFileLock(fs.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	values, leaves, err := fs.load()
+	if err != nil {
+		return err
+	}
+	if !fn(values) {
+		return nil
+	}
+
+	data, err := marshalNested(values, leaves, fs.format)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if err := writeFileAtomic(fs.path, data); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+// acquireFileLock 通过独占创建锁文件实现跨进程互斥，超过 fileLockStaleAfter 的锁视为残留并清理
+func acquireFileLock(path string) (func(), error) {
+	deadline := time.Now().Add(fileLockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			_, _ = f.WriteString(strconv.Itoa(os.Getpid()))
+			_ = f.Close()
+			return func() { _ = os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("%w: create lock file: %v", ErrStorageOperation, err)
+		}
+
+		if removeStaleLock(path) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w: timed out waiting for lock %s", ErrStorageOperation, path)
+		}
+		time.Sleep(fileLockRetryInterval)
+	}
+}
+
+// removeStaleLock 在持有守护锁 path+".stale" 时重新检查并删除残留的锁文件，
+// 避免两个进程先后判断为残留后，其中一个删除另一个刚刚创建的锁
+func removeStaleLock(path string) bool {
+	if !isStale(path) {
+		return false
+	}
+
+	guard := path + ".stale"
+	f, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		// 守护锁只在删除期间短暂持有，残留时说明持有者已退出
+		if isStale(guard) {
+			_ = os.Remove(guard)
+		}
+		return false
+	}
+	_ = f.Close()
+	defer func() { _ = os.Remove(guard) }()
+
+	if !isStale(path) {
+		return false
+	}
+	return os.Remove(path) == nil
+}
+
+// isStale 判断锁文件是否存在且超过 fileLockStaleAfter 未更新
+func isStale(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > fileLockStaleAfter
+}
+
+// writeFileAtomic 先写入同目录下的临时文件并 fsync，再 rename 覆盖目标文件
+func writeFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpName := tmp.Name()
+	defer func() { _ = os.Remove(tmpName) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return err
+	}
+
+	// 同步目录，确保 rename 持久化
+	if d, err := os.Open(dir); err == nil {
+		_ = d.Sync()
+		_ = d.Close()
+	}
+	return nil
+}
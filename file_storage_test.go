@@ -0,0 +1,196 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileStorage_Formats(t *testing.T) {
+	for _, format := range []Format{FormatJSON, FormatYAML, FormatTOML} {
+		t.Run(string(format), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "settings."+string(format))
+			storage, err := NewFileStorage(path, format)
+			require.NoError(t, err)
+
+			_, err = storage.Get("app.db.host")
+			assert.ErrorIs(t, err, ErrKeyNotFound)
+
+			require.NoError(t, storage.Set("app.db.host", "localhost"))
+			require.NoError(t, storage.Set("app.db.port", "5432"))
+			require.NoError(t, storage.Set("app.name", "demo"))
+
+			// 重新打开后数据仍然存在
+			reopened, err := NewFileStorage(path, format)
+			require.NoError(t, err)
+			value, err := reopened.Get("app.db.host")
+			require.NoError(t, err)
+			assert.Equal(t, "localhost", value)
+
+			keys, err := reopened.Keys("app.db.")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"app.db.host", "app.db.port"}, keys)
+
+			require.NoError(t, reopened.Delete("app.db.host"))
+			_, err = storage.Get("app.db.host")
+			assert.ErrorIs(t, err, ErrKeyNotFound)
+
+			// 不应残留临时文件或锁文件
+			entries, err := os.ReadDir(filepath.Dir(path))
+			require.NoError(t, err)
+			assert.Len(t, entries, 1)
+		})
+	}
+}
+
+func TestFileStorage_NestedLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("app:\n  db:\n    host: db.local\n    port: 5432\n  debug: true\n"), 0o644))
+
+	storage, err := NewFileStorage(path, FormatYAML)
+	require.NoError(t, err)
+
+	port, err := storage.Get("app.db.port")
+	require.NoError(t, err)
+	assert.Equal(t, "5432", port)
+
+	debug, err := storage.Get("app.debug")
+	require.NoError(t, err)
+	assert.Equal(t, "true", debug)
+
+	// 键与已有的嵌套对象冲突
+	err = storage.Set("app.db", "oops")
+	assert.ErrorIs(t, err, ErrStorageOperation)
+}
+
+func TestFileStorage_PreservesScalarTypes(t *testing.T) {
+	for _, tc := range []struct {
+		format   Format
+		original string
+		expected string
+	}{
+		{FormatYAML, "app:\n  port: 8080\n  debug: true\n  ratio: 0.5\n", "app:\n    debug: false\n    name: demo\n    port: 9090\n    ratio: 0.5\n"},
+		{FormatJSON, `{"app": {"port": 8080, "debug": true, "ratio": 0.5}}`, "{\n  \"app\": {\n    \"debug\": false,\n    \"name\": \"demo\",\n    \"port\": 9090,\n    \"ratio\": 0.5\n  }\n}\n"},
+		{FormatTOML, "[app]\nport = 8080\ndebug = true\nratio = 0.5\n", "[app]\ndebug = false\nname = 'demo'\nport = 9090\nratio = 0.5\n"},
+	} {
+		t.Run(string(tc.format), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "settings."+string(tc.format))
+			require.NoError(t, os.WriteFile(path, []byte(tc.original), 0o644))
+			storage, err := NewFileStorage(path, tc.format)
+			require.NoError(t, err)
+
+			require.NoError(t, storage.Set("app.name", "demo"))
+			require.NoError(t, storage.Set("app.port", "9090"))
+			require.NoError(t, storage.Set("app.debug", "false"))
+
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, string(data))
+		})
+	}
+
+	t.Run("value no longer fits the type", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "settings.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("app:\n  port: 8080\n"), 0o644))
+		storage, err := NewFileStorage(path, FormatYAML)
+		require.NoError(t, err)
+
+		require.NoError(t, storage.Set("app.port", "0080"))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "app:\n    port: \"0080\"\n", string(data))
+	})
+}
+
+func TestFileStorage_PreservesUntouchedLeaves(t *testing.T) {
+	for _, tc := range []struct {
+		format   Format
+		original string
+	}{
+		{FormatYAML, "app:\n  hosts: [a, b]\n  extra: {}\n  missing: null\n"},
+		{FormatJSON, `{"app": {"hosts": ["a", "b"], "extra": {}, "missing": null}}`},
+		// TOML 不支持 null
+		{FormatTOML, "[app]\nhosts = ['a', 'b']\n\n[app.extra]\n"},
+	} {
+		t.Run(string(tc.format), func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "settings."+string(tc.format))
+			require.NoError(t, os.WriteFile(path, []byte(tc.original), 0o644))
+			storage, err := NewFileStorage(path, tc.format)
+			require.NoError(t, err)
+
+			before, err := os.ReadFile(path)
+			require.NoError(t, err)
+			expected, err := decodeTree(before, tc.format)
+			require.NoError(t, err)
+
+			require.NoError(t, storage.Set("app.name", "demo"))
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			actual, err := decodeTree(data, tc.format)
+			require.NoError(t, err)
+
+			expected["app"].(map[string]any)["name"] = "demo"
+			assert.Equal(t, expected, actual)
+
+			// 空对象下仍可以新增键
+			require.NoError(t, storage.Set("app.extra.key", "value"))
+			value, err := storage.Get("app.extra.key")
+			require.NoError(t, err)
+			assert.Equal(t, "value", value)
+		})
+	}
+}
+
+func TestAcquireFileLock_Stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json.lock")
+	require.NoError(t, os.WriteFile(path, []byte("1"), 0o644))
+	old := time.Now().Add(-2 * fileLockStaleAfter)
+	require.NoError(t, os.Chtimes(path, old, old))
+
+	unlock, err := acquireFileLock(path)
+	require.NoError(t, err)
+	assert.NoFileExists(t, path+".stale")
+
+	// 新建的锁不会被当作残留删除
+	assert.False(t, removeStaleLock(path))
+	assert.FileExists(t, path)
+	unlock()
+	assert.NoFileExists(t, path)
+}
+
+func TestFileStorage_Concurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "settings.json")
+
+	// 使用两个实例模拟不同进程
+	first, err := NewFileStorage(path, FormatJSON)
+	require.NoError(t, err)
+	second, err := NewFileStorage(path, FormatJSON)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i, storage := range []*FileStorage{first, second} {
+		wg.Add(1)
+		go func(id int, storage *FileStorage) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				assert.NoError(t, storage.Set(fmt.Sprintf("worker%d.key%d", id, j), "value"))
+			}
+		}(i, storage)
+	}
+	wg.Wait()
+
+	keys, err := first.Keys("")
+	require.NoError(t, err)
+	assert.Len(t, keys, 40)
+}
+
+func TestNewFileStorage_UnsupportedFormat(t *testing.T) {
+	_, err := NewFileStorage("settings.ini", Format("ini"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,345 @@
+package conf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// errKeyConflict 表示键无法展开为嵌套对象，例如同时存在 "a" 和 "a.b"
+var errKeyConflict = errors.New("conflicting keys")
+
+// Format 表示配置文件的格式
+type Format string
+
+const (
+	FormatJSON Format = "json"
+	FormatYAML Format = "yaml"
+	FormatTOML Format = "toml"
+	// FormatDotenv 每行一个 "key=value"，键保持点分隔的形式
+	FormatDotenv Format = "dotenv"
+)
+
+// ParseFormat 根据名称或文件扩展名解析格式
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(strings.TrimPrefix(name, ".")) {
+	case "json":
+		return FormatJSON, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	case "toml":
+		return FormatTOML, nil
+	case "env", "dotenv":
+		return FormatDotenv, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q", name)
+	}
+}
+
+// marshalNested 将点分隔的键值展开为嵌套对象后按格式序列化。
+// leaves 为文件中原有的叶子值（可为 nil），值未改变类型时按原有的标量类型输出。
+func marshalNested(values map[string]string, leaves map[string]any, format Format) ([]byte, error) {
+	if format == FormatDotenv {
+		return marshalDotenv(values), nil
+	}
+
+	tree, err := unflattenKeys(values, leaves)
+	if err != nil {
+		return nil, err
+	}
+	return marshalTree(tree, format)
+}
+
+// marshalFlat 按格式序列化，键保持点分隔的形式而不展开为嵌套对象，
+// 用于同时存在 "a" 和 "a.b" 这类无法展开的键
+func marshalFlat(values map[string]string, format Format) ([]byte, error) {
+	if format == FormatDotenv {
+		return marshalDotenv(values), nil
+	}
+
+	tree := make(map[string]any, len(values))
+	for key, value := range values {
+		tree[key] = value
+	}
+	return marshalTree(tree, format)
+}
+
+// marshalTree 按格式序列化对象
+func marshalTree(tree map[string]any, format Format) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(tree, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return append(data, '\n'), nil
+	case FormatYAML:
+		return yaml.Marshal(tree)
+	case FormatTOML:
+		return toml.Marshal(tree)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// unmarshalNested 按格式解析嵌套对象，并展开为点分隔的键值
+func unmarshalNested(data []byte, format Format) (map[string]string, error) {
+	values, _, err := unmarshalLeaves(data, format)
+	return values, err
+}
+
+// unmarshalLeaves 与 unmarshalNested 相同，同时返回解析得到的原始叶子值（dotenv 为 nil）
+func unmarshalLeaves(data []byte, format Format) (map[string]string, map[string]any, error) {
+	if format == FormatDotenv {
+		values, err := parseDotenv(data)
+		return values, nil, err
+	}
+
+	tree, err := decodeTree(data, format)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make(map[string]string)
+	leaves := make(map[string]any)
+	if err := flattenKeys("", tree, values, leaves); err != nil {
+		return nil, nil, err
+	}
+	return values, leaves, nil
+}
+
+// decodeTree 按格式解析嵌套对象
+func decodeTree(data []byte, format Format) (map[string]any, error) {
+	tree := make(map[string]any)
+	var err error
+	switch format {
+	case FormatJSON:
+		err = json.Unmarshal(data, &tree)
+	case FormatYAML:
+		err = yaml.Unmarshal(data, &tree)
+	case FormatTOML:
+		err = toml.Unmarshal(data, &tree)
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tree, nil
+}
+
+// flattenKeys 将嵌套对象展开为点分隔的键，叶子节点转换为存储使用的字符串，原始叶子值写入 leaves。
+// 空对象作为叶子保留（值为 "{}"），以便写回文件时不会丢失。
+func flattenKeys(prefix string, node any, out map[string]string, leaves map[string]any) error {
+	if m, ok := node.(map[string]any); ok && (len(m) > 0 || prefix == "") {
+		for k, v := range m {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			if err := flattenKeys(key, v, out, leaves); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	value, err := scalarString(node)
+	if err != nil {
+		return fmt.Errorf("encode %s error: %w", prefix, err)
+	}
+	out[prefix] = value
+	leaves[prefix] = node
+	return nil
+}
+
+// scalarString 将解析得到的叶子值转换为字符串，与 SettingManager.Set 的编码保持一致
+func scalarString(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+}
+
+// unflattenKeys 将点分隔的键还原为嵌套对象，leaves 中的原有叶子值用于保留未修改的叶子和标量类型
+func unflattenKeys(values map[string]string, leaves map[string]any) (map[string]any, error) {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tree := make(map[string]any)
+	for _, key := range keys {
+		parts := strings.Split(key, ".")
+		node := tree
+		for i, part := range parts[:len(parts)-1] {
+			child, exists := node[part]
+			if !exists {
+				next := make(map[string]any)
+				node[part] = next
+				node = next
+				continue
+			}
+			next, ok := child.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("%w: key %q conflicts with %q", errKeyConflict, key, strings.Join(parts[:i+1], "."))
+			}
+			node = next
+		}
+
+		leaf := parts[len(parts)-1]
+		if _, exists := node[leaf]; exists {
+			return nil, fmt.Errorf("%w: key %q conflicts with a nested key", errKeyConflict, key)
+		}
+		original, ok := leaves[key]
+		if s, err := scalarString(original); ok && err == nil && s == values[key] {
+			// 值未改变时原样写回原有叶子，列表、空对象和 null 不会变成字符串
+			node[leaf] = original
+			continue
+		}
+		node[leaf] = typedLeaf(values[key], original)
+	}
+	return tree, nil
+}
+
+// typedLeaf 原有叶子为布尔值或数字、且新值能无损地按该类型表示时按原类型输出，否则输出字符串
+func typedLeaf(value string, original any) any {
+	switch original.(type) {
+	case bool:
+		if value == "true" || value == "false" {
+			return value == "true"
+		}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil && strconv.FormatInt(i, 10) == value {
+			return i
+		}
+	case float32, float64:
+		if f, err := strconv.ParseFloat(value, 64); err == nil && strconv.FormatFloat(f, 'g', -1, 64) == value {
+			return f
+		}
+	}
+	return value
+}
+
+// dotenv 双引号值中的转义
+var dotenvEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+
+// marshalDotenv 按键排序输出 "key=value"，值统一使用双引号
+func marshalDotenv(values map[string]string) []byte {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%s=\"%s\"\n", k, dotenvEscaper.Replace(values[k]))
+	}
+	return b.Bytes()
+}
+
+// parseDotenv 解析 dotenv 内容：支持注释、"export " 前缀、无引号、单引号（原样）
+// 和双引号（支持 \n \r \t \" \\ \$ 转义）的值，不展开变量
+func parseDotenv(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+
+		key, raw, ok := strings.Cut(text, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected key=value", line)
+		}
+		value, err := parseDotenvValue(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func parseDotenvValue(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, "'"):
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single-quoted value")
+		}
+		return raw[1 : end+1], checkDotenvTrailing(raw[end+2:])
+	case strings.HasPrefix(raw, `"`):
+		var b strings.Builder
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			switch {
+			case c == '"':
+				return b.String(), checkDotenvTrailing(raw[i+1:])
+			case c == '\\' && i+1 < len(raw):
+				i++
+				switch raw[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(raw[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double-quoted value")
+	default:
+		// 无引号的值到行内注释为止
+		if i := strings.Index(raw, " #"); i >= 0 {
+			raw = raw[:i]
+		}
+		return strings.TrimSpace(raw), nil
+	}
+}
+
+// checkDotenvTrailing 检查闭合引号之后的内容，只允许空白和行内注释
+func checkDotenvTrailing(rest string) error {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return fmt.Errorf("unexpected %q after closing quote", rest)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package conf
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GitOptions Git 存储的配置
+type GitOptions struct {
+	// AuthorName 提交作者名，默认为 "conf"
+	AuthorName string
+	// AuthorEmail 提交作者邮箱，默认为 "conf@localhost"
+	AuthorEmail string
+	// Message 生成提交信息，op 为 "set" 或 "delete"，默认为 "<op> <key>"
+	Message func(op, key string) string
+}
+
+// GitCommit 是一次配置修改对应的提交
+type GitCommit struct {
+	Hash    string
+	Author  string
+	Email   string
+	Time    time.Time
+	Message string
+}
+
+// GitStorage 将配置保存为本地 Git 仓库中的文件，目录层级映射为点分隔的键
+// （app.db.host ↔ app/db/host，与 DirStorage 相同），每次 Set/Delete 生成一次提交，
+// 从而可以用 git log、blame 和 diff 审阅配置的变更历史。依赖 PATH 中的 git 命令。
+type GitStorage struct {
+	dir   string
+	opts  GitOptions
+	mutex sync.Mutex
+}
+
+// NewGitStorage 打开 dir 处的 Git 仓库，仓库不存在时初始化一个新仓库
+func NewGitStorage(dir string, opts GitOptions) (*GitStorage, error) {
+	if opts.AuthorName == "" {
+		opts.AuthorName = "conf"
+	}
+	if opts.AuthorEmail == "" {
+		opts.AuthorEmail = "conf@localhost"
+	}
+	if opts.Message == nil {
+		opts.Message = func(op, key string) string { return op + " " + key }
+	}
+
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	gs := &GitStorage{dir: dir, opts: opts}
+	if _, err := os.Stat(filepath.Join(dir, ".git")); os.IsNotExist(err) {
+		if _, err := gs.git("init", "--quiet"); err != nil {
+			return nil, err
+		}
+	}
+	return gs, nil
+}
+
+// git 在仓库目录中执行 git 命令，返回标准输出
+func (gs *GitStorage) git(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = gs.dir
+	// 提交者身份不依赖全局配置
+	cmd.Env = append(os.Environ(),
+		"GIT_COMMITTER_NAME="+gs.opts.AuthorName,
+		"GIT_COMMITTER_EMAIL="+gs.opts.AuthorEmail,
+	)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%w: git %s: %v: %s", ErrStorageOperation, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// gitPath 将配置键转换为仓库内的相对路径
+func gitPath(key string) (string, error) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts {
+		if part == "" || strings.ContainsAny(part, `/\`) {
+			return "", fmt.Errorf("%w: invalid key %q", ErrStorageOperation, key)
+		}
+	}
+	return filepath.Join(parts...), nil
+}
+
+func (gs *GitStorage) Get(key string) (string, error) {
+	name, err := gitPath(key)
+	if err != nil {
+		return "", err
+	}
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	data, err := os.ReadFile(filepath.Join(gs.dir, name))
+	if os.IsNotExist(err) {
+		return "", ErrKeyNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return string(data), nil
+}
+
+func (gs *GitStorage) Set(key, value string) error {
+	name, err := gitPath(key)
+	if err != nil {
+		return err
+	}
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	path := filepath.Join(gs.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if err := writeFileAtomic(path, []byte(value)); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if _, err := gs.git("add", "--", name); err != nil {
+		gs.rollback(name)
+		return err
+	}
+	if err := gs.commit("set", key); err != nil {
+		gs.rollback(name)
+		return err
+	}
+	return nil
+}
+
+func (gs *GitStorage) Delete(key string) error {
+	name, err := gitPath(key)
+	if err != nil {
+		return err
+	}
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	if _, err := os.Stat(filepath.Join(gs.dir, name)); os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := gs.git("rm", "--quiet", "--", name); err != nil {
+		return err
+	}
+	if err := gs.commit("delete", key); err != nil {
+		gs.rollback(name)
+		return err
+	}
+	return nil
+}
+
+// rollback 在提交失败后将 name 的暂存区和工作区恢复为 HEAD 中的状态，
+// 避免未提交的修改被读取或混入下一次提交
+func (gs *GitStorage) rollback(name string) {
+	if _, err := gs.git("checkout", "--quiet", "HEAD", "--", name); err == nil {
+		return
+	}
+	// HEAD 中没有该文件（新建的键或仓库尚无提交）
+	_, _ = gs.git("rm", "--quiet", "--cached", "--force", "--ignore-unmatch", "--", name)
+	_ = os.Remove(filepath.Join(gs.dir, name))
+}
+
+// commit 提交暂存区的修改，没有修改时不生成提交
+func (gs *GitStorage) commit(op, key string) error {
+	if _, err := gs.git("diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+	author := fmt.Sprintf("%s <%s>", gs.opts.AuthorName, gs.opts.AuthorEmail)
+	_, err := gs.git("commit", "--quiet", "--no-verify", "--author", author, "-m", gs.opts.Message(op, key))
+	return err
+}
+
+// Keys 返回以 prefix 开头的所有键
+func (gs *GitStorage) Keys(prefix string) ([]string, error) {
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	var keys []string
+	err := filepath.WalkDir(gs.dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(gs.dir, path)
+		if err != nil {
+			return err
+		}
+		key := strings.ReplaceAll(filepath.ToSlash(rel), "/", ".")
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// History 返回修改过该键的提交，最新的在前
+func (gs *GitStorage) History(key string) ([]GitCommit, error) {
+	name, err := gitPath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	// 仓库还没有任何提交
+	if _, err := gs.git("rev-parse", "--verify", "--quiet", "HEAD"); err != nil {
+		return nil, nil
+	}
+	out, err := gs.git("log", "--format=%H%x00%an%x00%ae%x00%at%x00%s%x1e", "--", name)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []GitCommit
+	for _, record := range strings.Split(out, "\x1e") {
+		fields := strings.Split(strings.TrimSpace(record), "\x00")
+		if len(fields) != 5 {
+			continue
+		}
+		var unix int64
+		if _, err := fmt.Sscan(fields[3], &unix); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+		}
+		commits = append(commits, GitCommit{
+			Hash:    fields[0],
+			Author:  fields[1],
+			Email:   fields[2],
+			Time:    time.Unix(unix, 0),
+			Message: fields[4],
+		})
+	}
+	return commits, nil
+}
+
+// GetAt 返回键在指定提交时的值
+func (gs *GitStorage) GetAt(key, rev string) (string, error) {
+	name, err := gitPath(key)
+	if err != nil {
+		return "", err
+	}
+
+	gs.mutex.Lock()
+	defer gs.mutex.Unlock()
+
+	// 先把 rev 解析为提交哈希，避免以 - 开头的 rev 被当作 git 选项
+	if strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("%w: invalid revision %q", ErrStorageOperation, rev)
+	}
+	hash, err := gs.git("rev-parse", "--verify", "--quiet", "--end-of-options", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("%w: unknown revision %q", ErrStorageOperation, rev)
+	}
+
+	out, err := gs.git("show", strings.TrimSpace(hash)+":"+filepath.ToSlash(name))
+	if err != nil {
+		return "", ErrKeyNotFound
+	}
+	return out, nil
+}
@@ -0,0 +1,123 @@
+package conf
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestGitStorage(t *testing.T) *GitStorage {
+	t.Helper()
+
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	storage, err := NewGitStorage(t.TempDir(), GitOptions{AuthorName: "alice", AuthorEmail: "alice@example.com"})
+	require.NoError(t, err)
+	return storage
+}
+
+func TestGitStorage_Basic(t *testing.T) {
+	storage := newTestGitStorage(t)
+
+	_, err := storage.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.NoError(t, storage.Set("app.name", "demo"))
+	require.NoError(t, storage.Set("app.db.host", "localhost"))
+	require.NoError(t, storage.Set("app.db.port", "5432"))
+
+	value, err := storage.Get("app.db.host")
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", value)
+
+	keys, err := storage.Keys("app.db.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.db.host", "app.db.port"}, keys)
+
+	require.NoError(t, storage.Delete("app.name"))
+	_, err = storage.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.NoError(t, storage.Delete("app.name"))
+
+	assert.ErrorIs(t, storage.Set("app..name", "x"), ErrStorageOperation)
+}
+
+func TestGitStorage_History(t *testing.T) {
+	storage := newTestGitStorage(t)
+
+	history, err := storage.History("app.port")
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	require.NoError(t, storage.Set("app.port", "8080"))
+	require.NoError(t, storage.Set("app.port", "9090"))
+	// 值未变化时不生成提交
+	require.NoError(t, storage.Set("app.port", "9090"))
+	require.NoError(t, storage.Set("app.name", "demo"))
+
+	history, err = storage.History("app.port")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "set app.port", history[0].Message)
+	assert.Equal(t, "alice", history[0].Author)
+	assert.Equal(t, "alice@example.com", history[0].Email)
+
+	old, err := storage.GetAt("app.port", history[1].Hash)
+	require.NoError(t, err)
+	assert.Equal(t, "8080", old)
+
+	_, err = storage.GetAt("app.name", history[1].Hash)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 以 - 开头的 rev 不能被当作 git 选项
+	output := filepath.Join(t.TempDir(), "out")
+	_, err = storage.GetAt("app.port", "--output="+output)
+	assert.ErrorIs(t, err, ErrStorageOperation)
+	assert.NoFileExists(t, output)
+
+	_, err = storage.GetAt("app.port", "no-such-rev")
+	assert.ErrorIs(t, err, ErrStorageOperation)
+
+	require.NoError(t, storage.Delete("app.port"))
+	history, err = storage.History("app.port")
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, "delete app.port", history[0].Message)
+}
+
+func TestGitStorage_CommitFailure(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	// 空的提交信息会使 git commit 失败
+	storage, err := NewGitStorage(t.TempDir(), GitOptions{
+		AuthorName:  "alice",
+		AuthorEmail: "alice@example.com",
+		Message: func(op, key string) string {
+			if key == "app.broken" {
+				return ""
+			}
+			return op + " " + key
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Set("app.name", "demo"))
+	assert.ErrorIs(t, storage.Set("app.broken", "x"), ErrStorageOperation)
+	_, err = storage.Get("app.broken")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 失败的修改不会留在暂存区，也不会混入下一次提交
+	status, err := storage.git("status", "--porcelain")
+	require.NoError(t, err)
+	assert.Empty(t, status)
+
+	require.NoError(t, storage.Set("app.port", "8080"))
+	files, err := storage.git("show", "--name-only", "--format=", "HEAD")
+	require.NoError(t, err)
+	assert.Equal(t, "app/port\n", files)
+}
@@ -0,0 +1,97 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// settingRecord 是 settings 表中的一行
+type settingRecord struct {
+	Key   string `gorm:"column:key;primaryKey"`
+	Value string `gorm:"column:value;not null"`
+}
+
+func (settingRecord) TableName() string {
+	return "settings"
+}
+
+// GormStorage 将配置保存在数据库的 settings 表（key、value 两列）中。
+// conf 包不导入任何数据库驱动，SQLite 见 conf/sqlite 子包。
+type GormStorage struct {
+	db *gorm.DB
+}
+
+// NewGormStorage 使用已有的数据库连接创建存储，并自动创建 settings 表
+func NewGormStorage(db *gorm.DB) (*GormStorage, error) {
+	if err := db.AutoMigrate(&settingRecord{}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return &GormStorage{db: db}, nil
+}
+
+// DB 返回底层的数据库连接
+func (gs *GormStorage) DB() *gorm.DB {
+	return gs.db
+}
+
+func (gs *GormStorage) Get(key string) (string, error) {
+	var record settingRecord
+	err := gs.db.Where(&settingRecord{Key: key}).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrKeyNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return record.Value, nil
+}
+
+func (gs *GormStorage) Set(key, value string) error {
+	err := gs.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value"}),
+	}).Create(&settingRecord{Key: key, Value: value}).Error
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+func (gs *GormStorage) Delete(key string) error {
+	if err := gs.db.Where(&settingRecord{Key: key}).Delete(&settingRecord{}).Error; err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+// Keys 返回以 prefix 开头的所有键
+func (gs *GormStorage) Keys(prefix string) ([]string, error) {
+	var keys []string
+	err := gs.db.Model(&settingRecord{}).
+		Where(clause.Like{Column: clause.Column{Name: "key"}, Value: likePrefix(prefix) + "%"}).
+		Order(clause.OrderByColumn{Column: clause.Column{Name: "key"}}).
+		Pluck("key", &keys).Error
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	// LIKE 在部分数据库中不区分大小写，这里再精确过滤一次
+	filtered := keys[:0]
+	for _, k := range keys {
+		if strings.HasPrefix(k, prefix) {
+			filtered = append(filtered, k)
+		}
+	}
+	return filtered, nil
+}
+
+// likePrefix 截断到第一个 LIKE 通配符之前，之后的部分由调用方精确过滤
+func likePrefix(prefix string) string {
+	if i := strings.IndexAny(prefix, `%_\`); i >= 0 {
+		return prefix[:i]
+	}
+	return prefix
+}
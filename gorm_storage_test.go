@@ -0,0 +1,74 @@
+package conf
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestGormStorage(t *testing.T) *GormStorage {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "config.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	require.NoError(t, err)
+	storage, err := NewGormStorage(db)
+	require.NoError(t, err)
+	return storage
+}
+
+func TestGormStorage_BasicOperations(t *testing.T) {
+	storage := newTestGormStorage(t)
+
+	_, err := storage.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.NoError(t, storage.Set("app.name", "demo"))
+	require.NoError(t, storage.Set("app.name", "renamed"))
+	require.NoError(t, storage.Set("app.port", "8080"))
+	require.NoError(t, storage.Set("db.host", "localhost"))
+
+	value, err := storage.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "renamed", value)
+
+	keys, err := storage.Keys("app.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.name", "app.port"}, keys)
+
+	require.NoError(t, storage.Delete("app.name"))
+	_, err = storage.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
+
+func TestGormStorage_KeysWithWildcards(t *testing.T) {
+	storage := newTestGormStorage(t)
+
+	require.NoError(t, storage.Set("feature_flags.beta", "true"))
+	require.NoError(t, storage.Set("featureXflags.beta", "false"))
+	require.NoError(t, storage.Set("App.name", "demo"))
+
+	keys, err := storage.Keys("feature_")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"feature_flags.beta"}, keys)
+
+	keys, err = storage.Keys("app.")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+}
+
+func TestGormStorage_WithManager(t *testing.T) {
+	storage := newTestGormStorage(t)
+	manager := newSettingManager(storage)
+
+	require.NoError(t, manager.Set("app.port", 8080))
+	port, err := getTyped[int](manager, "app.port")
+	require.NoError(t, err)
+	assert.Equal(t, 8080, *port)
+}
@@ -0,0 +1,297 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Revision 是键的一次修改，Deleted 为 true 表示该修订删除了键
+type Revision struct {
+	ID      int64     `gorm:"primaryKey" json:"id"`
+	Key     string    `gorm:"index;not null" json:"key"`
+	Value   string    `gorm:"not null" json:"value"`
+	Deleted bool      `gorm:"not null" json:"deleted,omitempty"`
+	Time    time.Time `gorm:"index;not null" json:"time"`
+}
+
+// TableName 指定修订历史的表名
+func (Revision) TableName() string {
+	return "setting_revisions"
+}
+
+// HistoryStorage 包装存储，将每次写入和删除作为修订记录到数据库的 setting_revisions 表，
+// 实现 SettingHistory，使管理器可以查询历史并回滚。
+type HistoryStorage struct {
+	storage SettingStorage
+	db      *gorm.DB
+}
+
+// NewHistoryStorage 创建记录修订历史的存储，并自动创建 setting_revisions 表
+func NewHistoryStorage(storage SettingStorage, db *gorm.DB) (*HistoryStorage, error) {
+	if err := db.AutoMigrate(&Revision{}); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return &HistoryStorage{storage: storage, db: db}, nil
+}
+
+func (hs *HistoryStorage) Get(key string) (string, error) {
+	return hs.storage.Get(key)
+}
+
+func (hs *HistoryStorage) Set(key, value string) error {
+	return hs.apply(Revision{Key: key, Value: value}, func(storage SettingStorage) error {
+		return storage.Set(key, value)
+	})
+}
+
+func (hs *HistoryStorage) Delete(key string) error {
+	return hs.apply(Revision{Key: key, Deleted: true}, func(storage SettingStorage) error {
+		return storage.Delete(key)
+	})
+}
+
+// apply 执行写入并记录修订，保证不会出现没有修订的修改。
+// 底层存储是同一数据库上的 GormStorage 时两者在一个事务中完成；
+// 否则先记录修订，写入失败时再删除该修订。
+func (hs *HistoryStorage) apply(rev Revision, write func(storage SettingStorage) error) error {
+	if gs, ok := hs.storage.(*GormStorage); ok && sameDB(gs.db, hs.db) {
+		return hs.db.Transaction(func(tx *gorm.DB) error {
+			if err := write(&GormStorage{db: tx}); err != nil {
+				return err
+			}
+			return record(tx, &rev)
+		})
+	}
+
+	if err := record(hs.db, &rev); err != nil {
+		return err
+	}
+	if err := write(hs.storage); err != nil {
+		if rerr := hs.db.Delete(&Revision{}, rev.ID).Error; rerr != nil {
+			return fmt.Errorf("%w (remove revision %d: %v)", err, rev.ID, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+func record(db *gorm.DB, rev *Revision) error {
+	// 统一使用 UTC，保证按时间比较的结果与存储格式无关
+	rev.Time = time.Now().UTC()
+	if err := db.Create(rev).Error; err != nil {
+		return fmt.Errorf("%w: record revision: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+// sameDB 判断两个 gorm 连接是否使用同一个连接池
+func sameDB(a, b *gorm.DB) bool {
+	if a == b {
+		return true
+	}
+	da, err := a.DB()
+	if err != nil {
+		return false
+	}
+	db, err := b.DB()
+	return err == nil && da == db
+}
+
+// Keys 返回以 prefix 开头的所有键，底层存储不支持列举时返回错误
+func (hs *HistoryStorage) Keys(prefix string) ([]string, error) {
+	lister, ok := hs.storage.(SettingLister)
+	if !ok {
+		return nil, fmt.Errorf("%w: storage does not support listing", ErrStorageOperation)
+	}
+	return lister.Keys(prefix)
+}
+
+// Watch 转发底层存储的变更通知
+func (hs *HistoryStorage) Watch(fn func(key string)) (cancel func()) {
+	if watcher, ok := hs.storage.(SettingWatcher); ok {
+		return watcher.Watch(fn)
+	}
+	return func() {}
+}
+
+// History 返回键的所有修订，最新的在前
+func (hs *HistoryStorage) History(key string) ([]Revision, error) {
+	var revisions []Revision
+	if err := hs.db.Where(&Revision{Key: key}).Order("id desc").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return revisions, nil
+}
+
+// Revision 按 ID 返回修订
+func (hs *HistoryStorage) Revision(id int64) (*Revision, error) {
+	var rev Revision
+	err := hs.db.First(&rev, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return &rev, nil
+}
+
+// GetAt 返回键在 at 时刻的值
+func (hs *HistoryStorage) GetAt(key string, at time.Time) (string, error) {
+	var rev Revision
+	err := hs.db.Where(&Revision{Key: key}).Where("time <= ?", at.UTC()).Order("id desc").First(&rev).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", ErrKeyNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if rev.Deleted {
+		return "", ErrKeyNotFound
+	}
+	return rev.Value, nil
+}
+
+// RevisionsSince 返回 at 之后产生的所有修订，按时间先后排列
+func (hs *HistoryStorage) RevisionsSince(at time.Time) ([]Revision, error) {
+	var revisions []Revision
+	if err := hs.db.Where("time > ?", at.UTC()).Order("id").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return revisions, nil
+}
+
+// settingHistory 返回管理器存储层的修订历史
+func (sm *SettingManager) settingHistory() (SettingHistory, error) {
+	history, ok := sm.storage.(SettingHistory)
+	if !ok {
+		return nil, fmt.Errorf("%w: storage does not track history", ErrStorageOperation)
+	}
+	return history, nil
+}
+
+// History 返回键的所有修订，最新的在前，存储层需要实现 SettingHistory。
+// 敏感键的值会被隐藏。
+func (sm *SettingManager) History(key string) ([]Revision, error) {
+	history, err := sm.settingHistory()
+	if err != nil {
+		return nil, err
+	}
+	revisions, err := history.History(key)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if !revisions[i].Deleted {
+			revisions[i].Value = sm.redactString(key, revisions[i].Value)
+		}
+	}
+	return revisions, nil
+}
+
+// Rollback 将键恢复为指定修订时的值，删除类型的修订会删除该键。
+// 回滚本身也会产生新的修订和审计记录。
+func (sm *SettingManager) Rollback(key string, revision int64) error {
+	return sm.RollbackContext(context.Background(), key, revision)
+}
+
+// RollbackContext 与 Rollback 相同，ctx 提供授权检查使用的主体
+func (sm *SettingManager) RollbackContext(ctx context.Context, key string, revision int64) error {
+	history, err := sm.settingHistory()
+	if err != nil {
+		return err
+	}
+	rev, err := history.Revision(revision)
+	if err != nil {
+		return err
+	}
+	if rev.Key != key {
+		return fmt.Errorf("%w: revision %d belongs to %s, not %s", ErrStorageOperation, revision, rev.Key, key)
+	}
+
+	ctx = WithReason(ctx, fmt.Sprintf("rollback to revision %d", revision))
+	if rev.Deleted {
+		return sm.delete(ctx, key)
+	}
+	return sm.set(ctx, key, rev.Value)
+}
+
+// RollbackAll 将 at 之后修改过的所有键恢复为 at 时刻的值，当时不存在的键会被删除
+func (sm *SettingManager) RollbackAll(at time.Time) error {
+	return sm.RollbackAllContext(context.Background(), at)
+}
+
+// RollbackAllContext 与 RollbackAll 相同，ctx 提供授权检查使用的主体。
+// 设置了授权器时，在恢复任何键之前检查所有涉及的键的权限。
+func (sm *SettingManager) RollbackAllContext(ctx context.Context, at time.Time) error {
+	history, err := sm.settingHistory()
+	if err != nil {
+		return err
+	}
+	revisions, err := history.RevisionsSince(at)
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool)
+	values := make(map[string]string)
+	var writes, deletes []string
+	for _, rev := range revisions {
+		if seen[rev.Key] {
+			continue
+		}
+		seen[rev.Key] = true
+
+		value, err := history.GetAt(rev.Key, at)
+		switch {
+		case errors.Is(err, ErrKeyNotFound):
+			deletes = append(deletes, rev.Key)
+		case err != nil:
+			return fmt.Errorf("rollback %s: %w", rev.Key, err)
+		default:
+			values[rev.Key] = value
+			writes = append(writes, rev.Key)
+		}
+	}
+	if err := sm.authorizeWrites(ctx, writes, deletes); err != nil {
+		return err
+	}
+
+	ctx = WithReason(ctx, "rollback to "+at.Format(time.RFC3339))
+	for _, key := range writes {
+		if err := sm.set(ctx, key, values[key]); err != nil {
+			return fmt.Errorf("rollback %s: %w", key, err)
+		}
+	}
+	for _, key := range deletes {
+		if err := sm.delete(ctx, key); err != nil {
+			return fmt.Errorf("rollback %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// GetAt retrieves the value a setting had at the given time
+func GetAt[T any](key string, at time.Time) (*T, error) {
+	if _settingsManager == nil {
+		return nil, fmt.Errorf("settings manager not initialized")
+	}
+	return getTypedAt[T](_settingsManager, key, at)
+}
+
+// getTypedAt 从指定管理器的修订历史中读取配置并转换为目标类型
+func getTypedAt[T any](sm *SettingManager, key string, at time.Time) (*T, error) {
+	history, err := sm.settingHistory()
+	if err != nil {
+		return nil, err
+	}
+	value, err := history.GetAt(key, at)
+	if err != nil {
+		return nil, err
+	}
+	return parseStored[T](sm, key, value)
+}
@@ -0,0 +1,139 @@
+package conf
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestHistoryManager(t *testing.T) (*SettingManager, *mockStorage) {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "history.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	require.NoError(t, err)
+
+	backend := newMockStorage()
+	storage, err := NewHistoryStorage(backend, db)
+	require.NoError(t, err)
+	return newSettingManager(storage), backend
+}
+
+// pause 保证前后两次写入的时间戳不同
+func pause() time.Time {
+	time.Sleep(5 * time.Millisecond)
+	at := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	return at
+}
+
+func TestHistory_GetAtAndRollback(t *testing.T) {
+	manager, _ := newTestHistoryManager(t)
+	manager.MarkSensitive("*.password")
+
+	require.NoError(t, manager.Set("app.port", 8080))
+	beforeChange := pause()
+	require.NoError(t, manager.Set("app.port", 9090))
+	require.NoError(t, manager.Set("db.password", "hunter2"))
+
+	history, err := manager.History("app.port")
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "9090", history[0].Value)
+	assert.Equal(t, "8080", history[1].Value)
+
+	port, err := getTypedAt[int](manager, "app.port", beforeChange)
+	require.NoError(t, err)
+	assert.Equal(t, 8080, *port)
+
+	_, err = getTypedAt[string](manager, "db.password", beforeChange)
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	passwords, err := manager.History("db.password")
+	require.NoError(t, err)
+	assert.Equal(t, RedactedValue, passwords[0].Value)
+
+	require.NoError(t, manager.Rollback("app.port", history[1].ID))
+	port, err = getTyped[int](manager, "app.port")
+	require.NoError(t, err)
+	assert.Equal(t, 8080, *port)
+
+	assert.ErrorIs(t, manager.Rollback("db.password", history[1].ID), ErrStorageOperation)
+	assert.ErrorIs(t, manager.Rollback("app.port", 999), ErrKeyNotFound)
+}
+
+func TestHistory_RollbackAll(t *testing.T) {
+	manager, backend := newTestHistoryManager(t)
+
+	require.NoError(t, manager.Set("app.name", "demo"))
+	require.NoError(t, manager.Set("app.port", 8080))
+	require.NoError(t, manager.Set("old.flag", true))
+	goodState := pause()
+
+	// 一次错误的配置推送
+	require.NoError(t, manager.Set("app.port", 1))
+	require.NoError(t, manager.Set("app.port", 2))
+	require.NoError(t, manager.Delete("old.flag"))
+	require.NoError(t, manager.Set("new.flag", true))
+
+	require.NoError(t, manager.RollbackAll(goodState))
+	assert.Equal(t, map[string]string{
+		"app.name": "demo",
+		"app.port": "8080",
+		"old.flag": "true",
+	}, backend.data)
+
+	port, err := getTyped[int](manager, "app.port")
+	require.NoError(t, err)
+	assert.Equal(t, 8080, *port)
+}
+
+func TestHistory_Unsupported(t *testing.T) {
+	manager := newSettingManager(newMockStorage())
+	_, err := manager.History("app.port")
+	assert.ErrorIs(t, err, ErrStorageOperation)
+	assert.ErrorIs(t, manager.RollbackAll(time.Now()), ErrStorageOperation)
+}
+
+func TestHistory_WriteAndRecordAtomic(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "history.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	require.NoError(t, err)
+	backend, err := NewGormStorage(db)
+	require.NoError(t, err)
+	storage, err := NewHistoryStorage(backend, db)
+	require.NoError(t, err)
+
+	require.NoError(t, storage.Set("app.name", "v1"))
+	require.NoError(t, db.Migrator().DropTable(&Revision{}))
+
+	// 修订记录失败时写入一同回滚
+	assert.ErrorIs(t, storage.Set("app.name", "v2"), ErrStorageOperation)
+	assert.ErrorIs(t, storage.Delete("app.name"), ErrStorageOperation)
+	value, err := backend.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "v1", value)
+}
+
+func TestHistory_WriteFailureRemovesRevision(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "history.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	require.NoError(t, err)
+	storage, err := NewHistoryStorage(unavailableStorage{}, db)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, storage.Set("app.name", "v1"), ErrStorageOperation)
+	assert.ErrorIs(t, storage.Delete("app.name"), ErrStorageOperation)
+	revisions, err := storage.History("app.name")
+	require.NoError(t, err)
+	assert.Empty(t, revisions)
+}
@@ -0,0 +1,365 @@
+package conf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 设置接口的路径前缀
+const settingsPath = "/settings/"
+
+// 单个配置值的最大长度
+const maxSettingBodySize = 1 << 20
+
+// HTTPStorage 默认客户端的请求超时时间
+const defaultHTTPTimeout = 10 * time.Second
+
+// NewSettingHandler 创建通过 REST 接口暴露存储的 http.Handler：
+//
+//	GET    /settings/{key}        读取值，响应头 ETag 为版本
+//	PUT    /settings/{key}        写入值，支持 If-Match 和 If-None-Match: *
+//	DELETE /settings/{key}        删除，支持 If-Match
+//	GET    /settings/?prefix=app. 列出以 prefix 开头的键
+//
+// 存储实现 VersionedStorage 时 ETag 为版本号，条件写入和删除是原子的；
+// 否则 ETag 为值的摘要，条件写入为先比较后写入。
+// 内部错误只向客户端返回状态文本，详细信息交给 opts.OnError。
+func NewSettingHandler(storage SettingStorage, opts SettingHandlerOptions) http.Handler {
+	h := &settingHandler{storage: storage, opts: opts}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+settingsPath+"{$}", h.list)
+	mux.HandleFunc("GET "+settingsPath+"{key...}", h.get)
+	mux.HandleFunc("PUT "+settingsPath+"{key...}", h.put)
+	mux.HandleFunc("DELETE "+settingsPath+"{key...}", h.delete)
+	return mux
+}
+
+// SettingHandlerOptions NewSettingHandler 的配置
+type SettingHandlerOptions struct {
+	// OnError 在请求因内部错误失败（响应 500）时调用，用于记录日志，为 nil 时忽略
+	OnError func(r *http.Request, err error)
+}
+
+type settingHandler struct {
+	storage SettingStorage
+	opts    SettingHandlerOptions
+}
+
+// current 返回键的当前值和 ETag
+func (h *settingHandler) current(key string) (string, string, error) {
+	if vs, ok := h.storage.(VersionedStorage); ok {
+		value, version, err := vs.GetVersion(key)
+		if err != nil {
+			return "", "", err
+		}
+		return value, versionETag(version), nil
+	}
+	value, err := h.storage.Get(key)
+	if err != nil {
+		return "", "", err
+	}
+	return value, valueETag(value), nil
+}
+
+func (h *settingHandler) get(w http.ResponseWriter, r *http.Request) {
+	value, etag, err := h.current(r.PathValue("key"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = io.WriteString(w, value)
+}
+
+func (h *settingHandler) put(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSettingBodySize+1))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if len(body) > maxSettingBodySize {
+		http.Error(w, "setting value too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	value := string(body)
+
+	ifMatch := r.Header.Get("If-Match")
+	createOnly := r.Header.Get("If-None-Match") == "*"
+
+	// 支持版本的存储使用原子的比较并交换
+	if vs, ok := h.storage.(VersionedStorage); ok && (createOnly || (ifMatch != "" && ifMatch != "*")) {
+		var version int64
+		if !createOnly {
+			if version, err = parseVersionETag(ifMatch); err != nil {
+				h.writeError(w, r, ErrVersionConflict)
+				return
+			}
+		}
+		newVersion, err := vs.CompareAndSet(key, value, version)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		w.Header().Set("ETag", versionETag(newVersion))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if ifMatch != "" || createOnly {
+		if err := h.checkPrecondition(key, ifMatch, createOnly); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+	}
+	if err := h.storage.Set(key, value); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if _, etag, err := h.current(key); err == nil {
+		w.Header().Set("ETag", etag)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *settingHandler) delete(w http.ResponseWriter, r *http.Request) {
+	key := r.PathValue("key")
+	ifMatch := r.Header.Get("If-Match")
+
+	// 支持版本的存储使用原子的比较并删除
+	if vs, ok := h.storage.(VersionedStorage); ok && ifMatch != "" && ifMatch != "*" {
+		version, err := parseVersionETag(ifMatch)
+		if err != nil {
+			h.writeError(w, r, ErrVersionConflict)
+			return
+		}
+		if err := vs.CompareAndDelete(key, version); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if ifMatch != "" {
+		if err := h.checkPrecondition(key, ifMatch, false); err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+	}
+	if err := h.storage.Delete(key); err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkPrecondition 检查 If-Match / If-None-Match 条件
+func (h *settingHandler) checkPrecondition(key, ifMatch string, createOnly bool) error {
+	_, etag, err := h.current(key)
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		if createOnly {
+			return nil
+		}
+		return ErrVersionConflict
+	case err != nil:
+		return err
+	case createOnly || (ifMatch != "*" && ifMatch != etag):
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+func (h *settingHandler) list(w http.ResponseWriter, r *http.Request) {
+	lister, ok := h.storage.(SettingLister)
+	if !ok {
+		http.Error(w, "storage does not support listing", http.StatusNotImplemented)
+		return
+	}
+	keys, err := lister.Keys(r.URL.Query().Get("prefix"))
+	if err != nil {
+		h.writeError(w, r, err)
+		return
+	}
+	if keys == nil {
+		keys = []string{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(settingKeysResponse{Keys: keys})
+}
+
+type settingKeysResponse struct {
+	Keys []string `json:"keys"`
+}
+
+// writeError 将存储错误映射为 HTTP 状态码。
+// 响应体只包含状态文本，避免向客户端暴露存储路径或驱动错误，内部错误交给 OnError。
+func (h *settingHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrKeyNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrVersionConflict):
+		status = http.StatusPreconditionFailed
+	case errors.Is(err, ErrReadOnlyStorage):
+		// 只读存储只支持读取
+		status = http.StatusMethodNotAllowed
+		w.Header().Set("Allow", http.MethodGet)
+	default:
+		if h.opts.OnError != nil {
+			h.opts.OnError(r, err)
+		}
+	}
+	http.Error(w, http.StatusText(status), status)
+}
+
+func versionETag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func parseVersionETag(etag string) (int64, error) {
+	return strconv.ParseInt(strings.Trim(etag, `"`), 10, 64)
+}
+
+func valueETag(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// HTTPStorage 是 NewSettingHandler 所暴露接口的客户端，
+// 使轻量服务无需数据库凭据即可从中心配置服务读取配置。
+type HTTPStorage struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHTTPStorage 创建 HTTP 存储客户端，baseURL 为配置服务地址（不含 /settings/），
+// client 为 nil 时使用超时为 defaultHTTPTimeout 的客户端，可通过自定义 Transport 添加认证信息
+func NewHTTPStorage(baseURL string, client *http.Client) *HTTPStorage {
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	return &HTTPStorage{baseURL: strings.TrimRight(baseURL, "/"), client: client}
+}
+
+func (hs *HTTPStorage) settingURL(key string) string {
+	return hs.baseURL + settingsPath + (&url.URL{Path: key}).EscapedPath()
+}
+
+// do 发送请求并将错误状态码映射为存储错误
+func (hs *HTTPStorage) do(method, u string, body io.Reader, header http.Header) (*http.Response, []byte, error) {
+	req, err := http.NewRequest(method, u, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	for k, values := range header {
+		req.Header[k] = values
+	}
+
+	resp, err := hs.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusNoContent:
+		return resp, data, nil
+	case http.StatusNotFound:
+		return nil, nil, ErrKeyNotFound
+	case http.StatusPreconditionFailed:
+		return nil, nil, ErrVersionConflict
+	case http.StatusMethodNotAllowed:
+		return nil, nil, ErrReadOnlyStorage
+	default:
+		return nil, nil, fmt.Errorf("%w: %s %s: %s", ErrStorageOperation, method, u, resp.Status)
+	}
+}
+
+func (hs *HTTPStorage) Get(key string) (string, error) {
+	value, _, err := hs.GetETag(key)
+	return value, err
+}
+
+// GetETag 返回键的值及其 ETag
+func (hs *HTTPStorage) GetETag(key string) (string, string, error) {
+	resp, data, err := hs.do(http.MethodGet, hs.settingURL(key), nil, nil)
+	if err != nil {
+		return "", "", err
+	}
+	return string(data), resp.Header.Get("ETag"), nil
+}
+
+func (hs *HTTPStorage) Set(key, value string) error {
+	_, err := hs.SetIfMatch(key, value, "")
+	return err
+}
+
+// SetIfMatch 仅当键的 ETag 等于 etag 时写入，返回新的 ETag。
+// etag 为空表示无条件写入，"*" 表示键必须已存在；条件不满足时返回 ErrVersionConflict。
+func (hs *HTTPStorage) SetIfMatch(key, value, etag string) (string, error) {
+	header := http.Header{"Content-Type": {"text/plain; charset=utf-8"}}
+	if etag != "" {
+		header.Set("If-Match", etag)
+	}
+	resp, _, err := hs.do(http.MethodPut, hs.settingURL(key), strings.NewReader(value), header)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+// Create 仅当键不存在时写入
+func (hs *HTTPStorage) Create(key, value string) (string, error) {
+	header := http.Header{"If-None-Match": {"*"}}
+	resp, _, err := hs.do(http.MethodPut, hs.settingURL(key), strings.NewReader(value), header)
+	if err != nil {
+		return "", err
+	}
+	return resp.Header.Get("ETag"), nil
+}
+
+func (hs *HTTPStorage) Delete(key string) error {
+	_, _, err := hs.do(http.MethodDelete, hs.settingURL(key), nil, nil)
+	if errors.Is(err, ErrKeyNotFound) {
+		return nil
+	}
+	return err
+}
+
+// DeleteIfMatch 仅当键的 ETag 等于 etag 时删除，条件不满足时返回 ErrVersionConflict
+func (hs *HTTPStorage) DeleteIfMatch(key, etag string) error {
+	_, _, err := hs.do(http.MethodDelete, hs.settingURL(key), nil, http.Header{"If-Match": {etag}})
+	return err
+}
+
+// Keys 返回以 prefix 开头的所有键
+func (hs *HTTPStorage) Keys(prefix string) ([]string, error) {
+	u := hs.baseURL + settingsPath + "?" + url.Values{"prefix": {prefix}}.Encode()
+	_, data, err := hs.do(http.MethodGet, u, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp settingKeysResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("%w: invalid response: %v", ErrStorageOperation, err)
+	}
+	return resp.Keys, nil
+}
@@ -0,0 +1,156 @@
+package conf
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPStorage_RoundTrip(t *testing.T) {
+	backend, err := NewFileStorage(filepath.Join(t.TempDir(), "settings.json"), FormatJSON)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(NewSettingHandler(backend, SettingHandlerOptions{}))
+	defer server.Close()
+	client := NewHTTPStorage(server.URL, nil)
+
+	_, err = client.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	require.NoError(t, client.Set("app.name", "demo"))
+	require.NoError(t, client.Set("app.db.host", "localhost"))
+	require.NoError(t, client.Set("app.db.port", "5432"))
+
+	value, err := client.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "demo", value)
+
+	keys, err := client.Keys("app.db.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.db.host", "app.db.port"}, keys)
+
+	keys, err = client.Keys("missing.")
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+
+	require.NoError(t, client.Delete("app.name"))
+	_, err = backend.Get("app.name")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 通过管理器使用
+	manager := newSettingManager(client)
+	port, err := getTyped[int](manager, "app.db.port")
+	require.NoError(t, err)
+	assert.Equal(t, 5432, *port)
+}
+
+func TestHTTPStorage_ETags(t *testing.T) {
+	server := httptest.NewServer(NewSettingHandler(newMockStorage(), SettingHandlerOptions{}))
+	defer server.Close()
+	client := NewHTTPStorage(server.URL, nil)
+
+	etag, err := client.Create("lock.owner", "a")
+	require.NoError(t, err)
+	assert.NotEmpty(t, etag)
+
+	_, err = client.Create("lock.owner", "b")
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	value, current, err := client.GetETag("lock.owner")
+	require.NoError(t, err)
+	assert.Equal(t, "a", value)
+	assert.Equal(t, etag, current)
+
+	newETag, err := client.SetIfMatch("lock.owner", "b", etag)
+	require.NoError(t, err)
+	assert.NotEqual(t, etag, newETag)
+
+	_, err = client.SetIfMatch("lock.owner", "c", etag)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+}
+
+func TestSettingHandler_VersionedStorage(t *testing.T) {
+	server := newFakeConsul(t, "")
+	backend := NewConsulStorage(ConsulOptions{Address: server.URL})
+	defer backend.Close()
+
+	api := httptest.NewServer(NewSettingHandler(backend, SettingHandlerOptions{}))
+	defer api.Close()
+	client := NewHTTPStorage(api.URL, nil)
+
+	etag, err := client.Create("app.port", "8080")
+	require.NoError(t, err)
+
+	_, version, err := backend.GetVersion("app.port")
+	require.NoError(t, err)
+	assert.Equal(t, versionETag(version), etag)
+
+	_, err = client.SetIfMatch("app.port", "9090", `"1"`)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	etag, err = client.SetIfMatch("app.port", "9090", etag)
+	require.NoError(t, err)
+
+	// 条件删除通过存储的 CompareAndDelete 原子完成
+	assert.ErrorIs(t, client.DeleteIfMatch("app.port", `"1"`), ErrVersionConflict)
+	require.NoError(t, client.DeleteIfMatch("app.port", etag))
+	_, err = backend.Get("app.port")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+	assert.ErrorIs(t, client.DeleteIfMatch("app.port", etag), ErrVersionConflict)
+}
+
+func TestSettingHandler_InternalErrorsAreNotExposed(t *testing.T) {
+	var reported []error
+	server := httptest.NewServer(NewSettingHandler(unavailableStorage{}, SettingHandlerOptions{
+		OnError: func(r *http.Request, err error) { reported = append(reported, err) },
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/settings/app.name")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Equal(t, http.StatusText(http.StatusInternalServerError)+"\n", string(body))
+	// 详细错误交给回调
+	require.Len(t, reported, 1)
+	assert.ErrorIs(t, reported[0], ErrStorageOperation)
+}
+
+func TestNewHTTPStorage_DefaultTimeout(t *testing.T) {
+	client := NewHTTPStorage("http://localhost", nil)
+	assert.Equal(t, defaultHTTPTimeout, client.client.Timeout)
+}
+
+func TestSettingHandler_ReadOnly(t *testing.T) {
+	env, err := NewEnvStorage(EnvOptions{Prefix: "MYAPP"})
+	require.NoError(t, err)
+
+	server := httptest.NewServer(NewSettingHandler(env, SettingHandlerOptions{}))
+	defer server.Close()
+
+	assert.ErrorIs(t, NewHTTPStorage(server.URL, nil).Set("app.name", "x"), ErrReadOnlyStorage)
+
+	req, err := http.NewRequest(http.MethodPut, server.URL+"/settings/app.name", strings.NewReader("x"))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+	assert.Equal(t, http.MethodGet, resp.Header.Get("Allow"))
+
+	req, err = http.NewRequest(http.MethodPost, server.URL+"/settings/app.name", nil)
+	require.NoError(t, err)
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
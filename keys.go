@@ -0,0 +1,24 @@
+package conf
+
+import "path"
+
+// matchKey 判断配置键是否匹配模式。
+// 模式使用 path.Match 语法，由于配置键以 "." 分隔，"*" 可以跨越多级，
+// 例如 "app.*" 匹配 "app.db.host"，"*.password" 匹配 "db.password"。
+func matchKey(pattern, key string) bool {
+	if pattern == key {
+		return true
+	}
+	ok, err := path.Match(pattern, key)
+	return err == nil && ok
+}
+
+// matchAnyKey 判断配置键是否匹配任意一个模式
+func matchAnyKey(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if matchKey(pattern, key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,321 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// WriteMode 决定 MultiStorage 将写入发送到哪些存储
+type WriteMode int
+
+const (
+	// WriteAll 同步写入所有存储，任意一个失败都返回错误（默认）
+	WriteAll WriteMode = iota
+	// WritePrimary 只写入主存储
+	WritePrimary
+	// WriteAsync 同步写入主存储，再在后台依次复制到其他存储
+	WriteAsync
+)
+
+// String 返回写入模式的名称
+func (m WriteMode) String() string {
+	switch m {
+	case WriteAll:
+		return "all"
+	case WritePrimary:
+		return "primary"
+	case WriteAsync:
+		return "async"
+	default:
+		return fmt.Sprintf("WriteMode(%d)", int(m))
+	}
+}
+
+// MultiOptions 组合存储的配置
+type MultiOptions struct {
+	// Mode 写入模式
+	Mode WriteMode
+	// Primary 主存储在列表中的下标，默认为 0
+	Primary int
+	// Backfill 为 true 时，从靠后的存储读到的值会回填到前面的存储（只读存储除外）
+	Backfill bool
+	// OnError 接收后台复制和回填失败的错误，index 为存储在列表中的下标
+	OnError func(index int, key string, err error)
+	// QueueSize 异步复制队列的长度，默认为 1024，队列满时写入会阻塞
+	QueueSize int
+	// Targets 接收写入的存储下标，为空时为全部存储。WriteAll 同步写入这些存储，
+	// WriteAsync 写入主存储后复制到其中的其他存储，WritePrimary 忽略该选项
+	Targets []int
+}
+
+// MultiStorage 将多个存储组合为一个：按顺序读取，返回第一个找到的值，
+// 并按写入模式把修改发送到主存储或全部存储。例如本地副本在前、远程服务在后，
+// 读取优先命中本地副本，写入发往远程服务后再异步复制到本地。
+type MultiStorage struct {
+	storages []SettingStorage
+	opts     MultiOptions
+
+	// targets 为 WriteAll 写入的存储下标，replicas 为 WriteAsync 复制的存储下标
+	targets  []int
+	replicas []int
+
+	queue chan multiWrite
+	done  chan struct{}
+
+	// writeMutex 使写入主存储和入队在并发写入之间保持同一顺序
+	writeMutex sync.Mutex
+
+	// mutex 保护 pending 和 closed；pending 为已提交但尚未复制完成的写入数
+	mutex   sync.Mutex
+	drained *sync.Cond
+	pending int
+	closed  bool
+}
+
+// multiWrite 是一次待复制的写入，deleted 为 true 时表示删除
+type multiWrite struct {
+	key     string
+	value   string
+	deleted bool
+}
+
+// NewMultiStorage 创建组合存储，storages 的顺序即读取顺序。
+// 使用 WriteAsync 时应在退出前调用 Close 等待复制完成。
+func NewMultiStorage(opts MultiOptions, storages ...SettingStorage) (*MultiStorage, error) {
+	if len(storages) == 0 {
+		return nil, fmt.Errorf("%w: no storages", ErrStorageOperation)
+	}
+	if opts.Primary < 0 || opts.Primary >= len(storages) {
+		return nil, fmt.Errorf("%w: primary index %d out of range", ErrStorageOperation, opts.Primary)
+	}
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = 1024
+	}
+
+	ms := &MultiStorage{storages: storages, opts: opts}
+	if len(opts.Targets) == 0 {
+		for i := range storages {
+			ms.targets = append(ms.targets, i)
+		}
+	} else {
+		seen := make(map[int]bool)
+		for _, i := range opts.Targets {
+			if i < 0 || i >= len(storages) {
+				return nil, fmt.Errorf("%w: target index %d out of range", ErrStorageOperation, i)
+			}
+			if !seen[i] {
+				seen[i] = true
+				ms.targets = append(ms.targets, i)
+			}
+		}
+		sort.Ints(ms.targets)
+	}
+	for _, i := range ms.targets {
+		if i != opts.Primary {
+			ms.replicas = append(ms.replicas, i)
+		}
+	}
+	ms.drained = sync.NewCond(&ms.mutex)
+	if opts.Mode == WriteAsync {
+		ms.queue = make(chan multiWrite, opts.QueueSize)
+		ms.done = make(chan struct{})
+		go ms.replicate()
+	}
+	return ms, nil
+}
+
+// Get 按顺序读取，返回第一个找到的值。所有存储都没有该键时返回 ErrKeyNotFound，
+// 否则若有存储出错（且其他存储都没有该键）返回第一个错误。
+func (ms *MultiStorage) Get(key string) (string, error) {
+	var firstErr error
+	for i, storage := range ms.storages {
+		value, err := storage.Get(key)
+		if err == nil {
+			if ms.opts.Backfill {
+				ms.backfill(i, key, value)
+			}
+			return value, nil
+		}
+		if !errors.Is(err, ErrKeyNotFound) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return "", ErrKeyNotFound
+}
+
+// backfill 将值写入下标 found 之前的存储
+func (ms *MultiStorage) backfill(found int, key, value string) {
+	for i := 0; i < found; i++ {
+		if err := ms.storages[i].Set(key, value); err != nil && !errors.Is(err, ErrReadOnlyStorage) {
+			ms.reportError(i, key, err)
+		}
+	}
+}
+
+func (ms *MultiStorage) Set(key, value string) error {
+	return ms.write(multiWrite{key: key, value: value})
+}
+
+func (ms *MultiStorage) Delete(key string) error {
+	return ms.write(multiWrite{key: key, deleted: true})
+}
+
+func (ms *MultiStorage) write(w multiWrite) error {
+	if ms.opts.Mode == WriteAll {
+		var errs []error
+		for _, i := range ms.targets {
+			err := ms.apply(i, w)
+			// 与回填一致，跳过主存储以外的只读存储
+			if err != nil && (i == ms.opts.Primary || !errors.Is(err, ErrReadOnlyStorage)) {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	if ms.opts.Mode != WriteAsync {
+		return ms.apply(ms.opts.Primary, w)
+	}
+
+	// 写入主存储和入队在 writeMutex 下完成，复制顺序与主存储的写入顺序一致
+	ms.writeMutex.Lock()
+	defer ms.writeMutex.Unlock()
+
+	// 先登记再写入主存储，Close 会等待登记的写入全部复制完成后才关闭队列
+	ms.mutex.Lock()
+	if ms.closed {
+		ms.mutex.Unlock()
+		return fmt.Errorf("%w: storage is closed", ErrStorageOperation)
+	}
+	ms.pending++
+	ms.mutex.Unlock()
+
+	if err := ms.apply(ms.opts.Primary, w); err != nil {
+		ms.finish()
+		return err
+	}
+	if len(ms.replicas) == 0 {
+		ms.finish()
+		return nil
+	}
+	ms.queue <- w
+	return nil
+}
+
+// finish 标记一次登记的写入已经完成
+func (ms *MultiStorage) finish() {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+
+	ms.pending--
+	if ms.pending == 0 {
+		ms.drained.Broadcast()
+	}
+}
+
+// waitDrained 在持有 mutex 时等待所有登记的写入完成
+func (ms *MultiStorage) waitDrained() {
+	for ms.pending > 0 {
+		ms.drained.Wait()
+	}
+}
+
+// apply 将写入应用到下标为 i 的存储
+func (ms *MultiStorage) apply(i int, w multiWrite) error {
+	if w.deleted {
+		return ms.storages[i].Delete(w.key)
+	}
+	return ms.storages[i].Set(w.key, w.value)
+}
+
+// replicate 按写入顺序将修改复制到主存储以外的存储
+func (ms *MultiStorage) replicate() {
+	defer close(ms.done)
+
+	for w := range ms.queue {
+		for _, i := range ms.replicas {
+			if err := ms.apply(i, w); err != nil && !errors.Is(err, ErrReadOnlyStorage) {
+				ms.reportError(i, w.key, err)
+			}
+		}
+		ms.finish()
+	}
+}
+
+func (ms *MultiStorage) reportError(index int, key string, err error) {
+	if ms.opts.OnError != nil {
+		ms.opts.OnError(index, key, err)
+	}
+}
+
+// Flush 等待已提交的异步复制完成
+func (ms *MultiStorage) Flush() {
+	ms.mutex.Lock()
+	defer ms.mutex.Unlock()
+	ms.waitDrained()
+}
+
+// Keys 返回所有支持列举的存储中以 prefix 开头的键的并集
+func (ms *MultiStorage) Keys(prefix string) ([]string, error) {
+	seen := make(map[string]struct{})
+	for _, storage := range ms.storages {
+		lister, ok := storage.(SettingLister)
+		if !ok {
+			continue
+		}
+		keys, err := lister.Keys(prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, k := range keys {
+			seen[k] = struct{}{}
+		}
+	}
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Watch 订阅所有支持变更通知的存储
+func (ms *MultiStorage) Watch(fn func(key string)) (cancel func()) {
+	var cancels []func()
+	for _, storage := range ms.storages {
+		if watcher, ok := storage.(SettingWatcher); ok {
+			cancels = append(cancels, watcher.Watch(fn))
+		}
+	}
+	return func() {
+		for _, c := range cancels {
+			c()
+		}
+	}
+}
+
+// Close 等待异步复制完成并停止后台任务，不会关闭组合中的存储。
+// 使用 WriteAsync 时，Close 之后的写入返回 ErrStorageOperation。
+func (ms *MultiStorage) Close() error {
+	if ms.queue == nil {
+		return nil
+	}
+
+	ms.mutex.Lock()
+	alreadyClosed := ms.closed
+	ms.closed = true
+	ms.waitDrained()
+	ms.mutex.Unlock()
+
+	if !alreadyClosed {
+		close(ms.queue)
+	}
+	<-ms.done
+	return nil
+}
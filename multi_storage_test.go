@@ -0,0 +1,219 @@
+package conf
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unavailableStorage 模拟不可用的存储，所有操作都返回错误
+type unavailableStorage struct{}
+
+func (unavailableStorage) Get(key string) (string, error) {
+	return "", fmt.Errorf("%w: connection refused", ErrStorageOperation)
+}
+
+func (unavailableStorage) Set(key, value string) error {
+	return fmt.Errorf("%w: connection refused", ErrStorageOperation)
+}
+
+func (unavailableStorage) Delete(key string) error {
+	return fmt.Errorf("%w: connection refused", ErrStorageOperation)
+}
+
+func TestMultiStorage_ReadOrder(t *testing.T) {
+	local, remote := newMockStorage(), newMockStorage()
+	require.NoError(t, local.Set("app.name", "local"))
+	require.NoError(t, remote.Set("app.name", "remote"))
+	require.NoError(t, remote.Set("app.port", "8080"))
+
+	storage, err := NewMultiStorage(MultiOptions{Backfill: true}, local, remote)
+	require.NoError(t, err)
+
+	value, err := storage.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "local", value)
+
+	value, err = storage.Get("app.port")
+	require.NoError(t, err)
+	assert.Equal(t, "8080", value)
+	// 回填到本地
+	value, err = local.Get("app.port")
+	require.NoError(t, err)
+	assert.Equal(t, "8080", value)
+
+	_, err = storage.Get("missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	// 前面的存储出错时继续读取后面的存储
+	storage, err = NewMultiStorage(MultiOptions{}, unavailableStorage{}, remote)
+	require.NoError(t, err)
+	value, err = storage.Get("app.port")
+	require.NoError(t, err)
+	assert.Equal(t, "8080", value)
+	_, err = storage.Get("missing")
+	assert.ErrorIs(t, err, ErrStorageOperation)
+}
+
+func TestMultiStorage_WriteModes(t *testing.T) {
+	t.Run("all", func(t *testing.T) {
+		first, second := newMockStorage(), newMockStorage()
+		storage, err := NewMultiStorage(MultiOptions{Mode: WriteAll}, first, second)
+		require.NoError(t, err)
+
+		require.NoError(t, storage.Set("app.name", "demo"))
+		assert.Equal(t, "demo", first.data["app.name"])
+		assert.Equal(t, "demo", second.data["app.name"])
+
+		storage, err = NewMultiStorage(MultiOptions{Mode: WriteAll}, first, unavailableStorage{})
+		require.NoError(t, err)
+		assert.ErrorIs(t, storage.Set("app.name", "other"), ErrStorageOperation)
+	})
+
+	t.Run("primary", func(t *testing.T) {
+		local, remote := newMockStorage(), newMockStorage()
+		storage, err := NewMultiStorage(MultiOptions{Mode: WritePrimary, Primary: 1}, local, remote)
+		require.NoError(t, err)
+
+		require.NoError(t, storage.Set("app.name", "demo"))
+		assert.Equal(t, "demo", remote.data["app.name"])
+		assert.NotContains(t, local.data, "app.name")
+	})
+
+	t.Run("async", func(t *testing.T) {
+		local, remote := newMockStorage(), newMockStorage()
+		var mutex sync.Mutex
+		var failed []int
+		storage, err := NewMultiStorage(MultiOptions{
+			Mode:    WriteAsync,
+			Primary: 1,
+			OnError: func(index int, key string, err error) {
+				mutex.Lock()
+				defer mutex.Unlock()
+				failed = append(failed, index)
+			},
+		}, local, remote, unavailableStorage{})
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			require.NoError(t, storage.Set("counter", fmt.Sprint(i)))
+		}
+		require.NoError(t, storage.Delete("counter"))
+		require.NoError(t, storage.Set("app.name", "demo"))
+		require.NoError(t, storage.Close())
+
+		assert.Equal(t, "demo", local.data["app.name"])
+		assert.NotContains(t, local.data, "counter")
+		assert.Len(t, failed, 12)
+		for _, index := range failed {
+			assert.Equal(t, 2, index)
+		}
+	})
+
+	t.Run("async after close", func(t *testing.T) {
+		local, remote := newMockStorage(), newMockStorage()
+		storage, err := NewMultiStorage(MultiOptions{Mode: WriteAsync, QueueSize: 1}, local, remote)
+		require.NoError(t, err)
+
+		// 并发写入与 Close 不会向已关闭的队列发送
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					err := storage.Set(fmt.Sprintf("worker%d.key%d", i, j), "value")
+					if err != nil {
+						assert.ErrorIs(t, err, ErrStorageOperation)
+						return
+					}
+					storage.Flush()
+				}
+			}(i)
+		}
+		require.NoError(t, storage.Close())
+		wg.Wait()
+		require.NoError(t, storage.Close())
+
+		assert.ErrorIs(t, storage.Set("app.name", "demo"), ErrStorageOperation)
+		assert.ErrorIs(t, storage.Delete("app.name"), ErrStorageOperation)
+		assert.Equal(t, local.data, remote.data)
+	})
+
+	t.Run("async concurrent writers", func(t *testing.T) {
+		local, remote := newMockStorage(), newMockStorage()
+		storage, err := NewMultiStorage(MultiOptions{Mode: WriteAsync, Primary: 1, QueueSize: 4}, local, remote)
+		require.NoError(t, err)
+
+		// 并发写入同一个键，复制后的最终值与主存储一致
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for j := 0; j < 50; j++ {
+					assert.NoError(t, storage.Set("counter", fmt.Sprintf("%d-%d", i, j)))
+				}
+			}(i)
+		}
+		wg.Wait()
+		require.NoError(t, storage.Close())
+		assert.Equal(t, remote.data["counter"], local.data["counter"])
+	})
+
+	t.Run("read-only secondaries", func(t *testing.T) {
+		primary := newMockStorage()
+		env, err := NewEnvStorage(EnvOptions{})
+		require.NoError(t, err)
+		storage, err := NewMultiStorage(MultiOptions{Mode: WriteAll}, primary, env)
+		require.NoError(t, err)
+		require.NoError(t, storage.Set("app.name", "demo"))
+		assert.Equal(t, "demo", primary.data["app.name"])
+
+		// 主存储只读时仍然返回错误
+		storage, err = NewMultiStorage(MultiOptions{Mode: WriteAll, Primary: 1}, primary, env)
+		require.NoError(t, err)
+		assert.ErrorIs(t, storage.Set("app.name", "demo"), ErrReadOnlyStorage)
+	})
+
+	t.Run("targets", func(t *testing.T) {
+		first, second, third := newMockStorage(), newMockStorage(), newMockStorage()
+		storage, err := NewMultiStorage(MultiOptions{Mode: WriteAll, Targets: []int{0, 2}}, first, second, third)
+		require.NoError(t, err)
+		require.NoError(t, storage.Set("app.name", "demo"))
+		assert.Equal(t, "demo", first.data["app.name"])
+		assert.NotContains(t, second.data, "app.name")
+		assert.Equal(t, "demo", third.data["app.name"])
+
+		first, second, third = newMockStorage(), newMockStorage(), newMockStorage()
+		storage, err = NewMultiStorage(MultiOptions{Mode: WriteAsync, Targets: []int{1}}, first, second, third)
+		require.NoError(t, err)
+		require.NoError(t, storage.Set("app.name", "demo"))
+		require.NoError(t, storage.Close())
+		assert.Equal(t, "demo", first.data["app.name"])
+		assert.Equal(t, "demo", second.data["app.name"])
+		assert.NotContains(t, third.data, "app.name")
+
+		_, err = NewMultiStorage(MultiOptions{Targets: []int{3}}, first, second, third)
+		assert.ErrorIs(t, err, ErrStorageOperation)
+	})
+}
+
+func TestMultiStorage_Keys(t *testing.T) {
+	first, err := NewFileStorage(t.TempDir()+"/a.json", FormatJSON)
+	require.NoError(t, err)
+	second, err := NewFileStorage(t.TempDir()+"/b.json", FormatJSON)
+	require.NoError(t, err)
+	require.NoError(t, first.Set("app.db.host", "localhost"))
+	require.NoError(t, second.Set("app.db.host", "remote"))
+	require.NoError(t, second.Set("app.db.port", "5432"))
+
+	storage, err := NewMultiStorage(MultiOptions{}, first, second, newMockStorage())
+	require.NoError(t, err)
+	keys, err := storage.Keys("app.db.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.db.host", "app.db.port"}, keys)
+}
@@ -0,0 +1,74 @@
+package conf
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RedactedValue 替代敏感配置值的占位符
+const RedactedValue = "[REDACTED]"
+
+// MarkSensitive 将匹配模式的键标记为敏感，例如 "*.password"、"auth.*"，语法同访问规则和编解码器的键模式。
+// 敏感键的值在错误信息、Explain、同步结果、审计和导出中会被替换为 RedactedValue，Get[T] 仍返回真实的值。
+func (sm *SettingManager) MarkSensitive(patterns ...string) {
+	sm.sensitive = append(sm.sensitive, patterns...)
+}
+
+// IsSensitive 判断键是否被标记为敏感
+func (sm *SettingManager) IsSensitive(key string) bool {
+	return matchAnyKey(sm.sensitive, key)
+}
+
+// Redact 返回可以安全输出的值：敏感键返回 RedactedValue，其他键原样返回
+func (sm *SettingManager) Redact(key string, value any) any {
+	if sm.IsSensitive(key) {
+		return RedactedValue
+	}
+	return value
+}
+
+// redactString 与 Redact 相同，用于字符串值
+func (sm *SettingManager) redactString(key, value string) string {
+	if sm.IsSensitive(key) {
+		return RedactedValue
+	}
+	return value
+}
+
+// redactError 从敏感键的错误信息中去除值，errors.Is/As 仍可访问原始错误
+func (sm *SettingManager) redactError(key, value string, err error) error {
+	if err == nil || !sm.IsSensitive(key) {
+		return err
+	}
+	return &redactedError{err: err, value: value}
+}
+
+// redactedError 包装错误并在 Error() 中替换掉敏感值
+type redactedError struct {
+	err   error
+	value string
+}
+
+func (e *redactedError) Error() string {
+	msg := e.err.Error()
+	if e.value == "" {
+		return msg
+	}
+	// 只替换带引号的值（如 strconv 错误中的 parsing "..."），避免短值误伤其他文本
+	return strings.ReplaceAll(msg, strconv.Quote(e.value), strconv.Quote(RedactedValue))
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// String 返回适合输出到日志的说明
+func (e *Explanation) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s = %v (from %s)", e.Key, e.Value, e.Source)
+	for _, layer := range e.Shadowed {
+		fmt.Fprintf(&b, "; shadows %s = %v", layer.Source, layer.Value)
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package conf
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedaction_Errors(t *testing.T) {
+	manager := newSettingManager(newMockStorage())
+	manager.MarkSensitive("*.password", "auth.*")
+
+	require.NoError(t, manager.Set("db.password", "hunter2"))
+	require.NoError(t, manager.Set("db.port", "hunter2"))
+
+	// 类型化读取仍返回真实值
+	password, err := getTyped[string](manager, "db.password")
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", *password)
+
+	_, err = getTyped[int](manager, "db.password")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "hunter2")
+	assert.Contains(t, err.Error(), RedactedValue)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+
+	// 只替换带引号的值，短值不会影响错误信息中的其他文本
+	require.NoError(t, manager.Set("app.password", "a"))
+	_, err = getTyped[int](manager, "app.password")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `"`+RedactedValue+`"`)
+	assert.Contains(t, err.Error(), "invalid syntax")
+	assert.NotContains(t, err.Error(), `"a"`)
+
+	// 未标记的键保留原始错误信息
+	_, err = getTyped[int](manager, "db.port")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "hunter2")
+
+	assert.True(t, manager.IsSensitive("auth.jwt.secret"))
+	assert.False(t, manager.IsSensitive("db.host"))
+	assert.Equal(t, RedactedValue, manager.Redact("auth.key", "abc"))
+	assert.Equal(t, "localhost", manager.Redact("db.host", "localhost"))
+}
+
+func TestRedaction_ExplainAndSync(t *testing.T) {
+	manager := newSettingManager(newMockStorage())
+	manager.MarkSensitive("*.password")
+	manager.SetViperMode(ViperDisabled)
+	require.NoError(t, manager.Set("db.password", "hunter2"))
+
+	explanation, err := manager.Explain("db.password")
+	require.NoError(t, err)
+	assert.Equal(t, RedactedValue, explanation.Value)
+	assert.NotContains(t, explanation.String(), "hunter2")
+
+	v := viper.New()
+	v.Set("db.password", "changed")
+	v.Set("db.host", "localhost")
+	result, err := manager.SyncFromViper(v, SyncDryRun)
+	require.NoError(t, err)
+	require.Len(t, result.Changed, 1)
+	assert.Equal(t, SyncChange{Key: "db.password", OldValue: RedactedValue, NewValue: RedactedValue}, result.Changed[0])
+	require.Len(t, result.Added, 1)
+	assert.Equal(t, "localhost", result.Added[0].NewValue)
+}
+
+func TestRedaction_KeyPatterns(t *testing.T) {
+	manager := newSettingManager(newMockStorage())
+	manager.MarkSensitive("*.password", "auth.*", "*.*token")
+
+	// 与访问规则使用同一套匹配规则，"*" 可以跨越 "."
+	for _, key := range []string{"db.password", "a.b.password", "auth.key", "auth.jwt.secret", "github.token", "svc.accesstoken"} {
+		assert.True(t, manager.IsSensitive(key), key)
+	}
+	for _, key := range []string{"password", "db.password_hint", "db.passwords", "author.name", "auth", "token.ttl", "db.host"} {
+		assert.False(t, manager.IsSensitive(key), key)
+	}
+}
+
+func TestRedaction_ResolvedSecretNotMarked(t *testing.T) {
+	t.Setenv("TEST_REDACT_SECRET", "s3cr3t-value")
+	manager := newSettingManager(newMockStorage())
+	require.NoError(t, manager.Set("service.limit", "secret://env/TEST_REDACT_SECRET"))
+
+	_, err := getTyped[int](manager, "service.limit")
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "s3cr3t-value")
+	assert.Contains(t, err.Error(), RedactedValue)
+	assert.ErrorIs(t, err, strconv.ErrSyntax)
+}
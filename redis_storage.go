@@ -0,0 +1,233 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisMode 决定配置在 Redis 中的存放方式
+type RedisMode int
+
+const (
+	// RedisHash 将所有配置保存在名为 Namespace 的哈希中（默认）
+	RedisHash RedisMode = iota
+	// RedisString 每个配置保存为独立的字符串键 "<Namespace>:<key>"
+	RedisString
+)
+
+// RedisOptions Redis 存储的配置
+type RedisOptions struct {
+	// Namespace 命名空间，默认为 "conf"
+	Namespace string
+	// Mode 存放方式
+	Mode RedisMode
+	// Channel 用于广播变更的 pub/sub 频道，默认为 "<Namespace>:changes"
+	Channel string
+}
+
+// RedisStorage 基于 Redis 的存储。每次写入都会在 pub/sub 频道上广播变化的键，
+// 其他实例通过 Watch 订阅后即可使各自管理器中的缓存失效。连接断开后自动重新订阅，
+// 并通知清空全部缓存。
+type RedisStorage struct {
+	client   redis.UniversalClient
+	opts     RedisOptions
+	notifier changeNotifier
+
+	subscribeOnce sync.Once
+	pubsub        *redis.PubSub
+	cancel        context.CancelFunc
+	mutex         sync.Mutex
+}
+
+// NewRedisStorage 使用已有的 Redis 客户端创建存储
+func NewRedisStorage(client redis.UniversalClient, opts RedisOptions) *RedisStorage {
+	if opts.Namespace == "" {
+		opts.Namespace = "conf"
+	}
+	if opts.Channel == "" {
+		opts.Channel = opts.Namespace + ":changes"
+	}
+	return &RedisStorage{client: client, opts: opts}
+}
+
+func (rs *RedisStorage) stringKey(key string) string {
+	return rs.opts.Namespace + ":" + key
+}
+
+func (rs *RedisStorage) Get(key string) (string, error) {
+	ctx := context.Background()
+
+	var value string
+	var err error
+	if rs.opts.Mode == RedisString {
+		value, err = rs.client.Get(ctx, rs.stringKey(key)).Result()
+	} else {
+		value, err = rs.client.HGet(ctx, rs.opts.Namespace, key).Result()
+	}
+	if errors.Is(err, redis.Nil) {
+		return "", ErrKeyNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return value, nil
+}
+
+func (rs *RedisStorage) Set(key, value string) error {
+	return rs.write(key, func(pipe redis.Pipeliner) {
+		if rs.opts.Mode == RedisString {
+			pipe.Set(context.Background(), rs.stringKey(key), value, 0)
+		} else {
+			pipe.HSet(context.Background(), rs.opts.Namespace, key, value)
+		}
+	})
+}
+
+func (rs *RedisStorage) Delete(key string) error {
+	return rs.write(key, func(pipe redis.Pipeliner) {
+		if rs.opts.Mode == RedisString {
+			pipe.Del(context.Background(), rs.stringKey(key))
+		} else {
+			pipe.HDel(context.Background(), rs.opts.Namespace, key)
+		}
+	})
+}
+
+// write 在同一个事务中执行写入并广播变化的键
+func (rs *RedisStorage) write(key string, fn func(pipe redis.Pipeliner)) error {
+	ctx := context.Background()
+	_, err := rs.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		fn(pipe)
+		pipe.Publish(ctx, rs.opts.Channel, key)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	return nil
+}
+
+// Keys 返回以 prefix 开头的所有键
+func (rs *RedisStorage) Keys(prefix string) ([]string, error) {
+	ctx := context.Background()
+
+	var keys []string
+	if rs.opts.Mode == RedisString {
+		pattern := escapeRedisPattern(rs.stringKey(prefix)) + "*"
+		iter := rs.client.Scan(ctx, 0, pattern, 0).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, strings.TrimPrefix(iter.Val(), rs.opts.Namespace+":"))
+		}
+		if err := iter.Err(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+		}
+	} else {
+		all, err := rs.client.HKeys(ctx, rs.opts.Namespace).Result()
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrStorageOperation, err)
+		}
+		for _, k := range all {
+			if strings.HasPrefix(k, prefix) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Watch 注册变更回调，第一次调用时开始订阅变更频道
+func (rs *RedisStorage) Watch(fn func(key string)) (cancel func()) {
+	cancel = rs.notifier.Watch(fn)
+	rs.subscribeOnce.Do(rs.subscribe)
+	return cancel
+}
+
+// 订阅相关参数
+const (
+	redisSubscribeTimeout    = 5 * time.Second
+	redisHealthCheckInterval = time.Minute
+	redisResubscribeDelay    = 100 * time.Millisecond
+)
+
+func (rs *RedisStorage) subscribe() {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rs.cancel = cancel
+	rs.pubsub = rs.client.Subscribe(ctx, rs.opts.Channel)
+	// 等待订阅确认，避免错过紧随其后的变更；失败时由 receive 继续重试
+	_, err := rs.pubsub.ReceiveTimeout(ctx, redisSubscribeTimeout)
+	go rs.receive(ctx, rs.pubsub, err == nil)
+}
+
+// receive 接收变更通知。连接断开后 go-redis 会在下一次接收时重新连接并订阅，
+// 订阅恢复后通知清空缓存，因为断线期间的变更通知已经丢失。
+func (rs *RedisStorage) receive(ctx context.Context, pubsub *redis.PubSub, subscribed bool) {
+	for {
+		msg, err := pubsub.ReceiveTimeout(ctx, redisHealthCheckInterval)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, redis.ErrClosed) {
+				return
+			}
+			// 空闲超时时用 PING 检查连接，PING 失败会触发重新连接
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() && pubsub.Ping(ctx) == nil {
+				continue
+			}
+			subscribed = false
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(redisResubscribeDelay):
+			}
+			continue
+		}
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if !subscribed {
+				subscribed = true
+				rs.notifier.notify("")
+			}
+		case *redis.Message:
+			rs.notifier.notify(m.Payload)
+		}
+	}
+}
+
+// Close 停止订阅变更频道，不会关闭传入的客户端
+func (rs *RedisStorage) Close() error {
+	rs.mutex.Lock()
+	defer rs.mutex.Unlock()
+
+	if rs.pubsub == nil {
+		return nil
+	}
+	rs.cancel()
+	err := rs.pubsub.Close()
+	rs.pubsub = nil
+	return err
+}
+
+// escapeRedisPattern 转义 SCAN MATCH 模式中的特殊字符
+func escapeRedisPattern(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,118 @@
+package conf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedisClient(t *testing.T) redis.UniversalClient {
+	t.Helper()
+	return newTestRedisClientAt(t, miniredis.RunT(t).Addr())
+}
+
+func newTestRedisClientAt(t *testing.T, addr string) redis.UniversalClient {
+	t.Helper()
+
+	client := redis.NewClient(&redis.Options{Addr: addr})
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+func TestRedisStorage_Modes(t *testing.T) {
+	for name, mode := range map[string]RedisMode{"hash": RedisHash, "string": RedisString} {
+		t.Run(name, func(t *testing.T) {
+			storage := NewRedisStorage(newTestRedisClient(t), RedisOptions{Namespace: "myapp", Mode: mode})
+
+			_, err := storage.Get("app.name")
+			assert.ErrorIs(t, err, ErrKeyNotFound)
+
+			require.NoError(t, storage.Set("app.name", "demo"))
+			require.NoError(t, storage.Set("app.db.host", "localhost"))
+			require.NoError(t, storage.Set("app.db.port", "5432"))
+
+			value, err := storage.Get("app.name")
+			require.NoError(t, err)
+			assert.Equal(t, "demo", value)
+
+			keys, err := storage.Keys("app.db.")
+			require.NoError(t, err)
+			assert.Equal(t, []string{"app.db.host", "app.db.port"}, keys)
+
+			require.NoError(t, storage.Delete("app.name"))
+			_, err = storage.Get("app.name")
+			assert.ErrorIs(t, err, ErrKeyNotFound)
+		})
+	}
+}
+
+func TestRedisStorage_CrossInstanceInvalidation(t *testing.T) {
+	client := newTestRedisClient(t)
+
+	// 两个实例共享同一个 Redis
+	first := NewRedisStorage(client, RedisOptions{})
+	second := NewRedisStorage(client, RedisOptions{})
+	defer first.Close()
+	defer second.Close()
+
+	writer := newSettingManager(first)
+	reader := newSettingManager(second)
+
+	require.NoError(t, writer.Set("feature.enabled", true))
+	value, err := getTyped[bool](reader, "feature.enabled")
+	require.NoError(t, err)
+	assert.True(t, *value)
+
+	// 另一实例写入后，读取方的缓存应失效
+	require.NoError(t, writer.Set("feature.enabled", false))
+	assert.Eventually(t, func() bool {
+		value, err := getTyped[bool](reader, "feature.enabled")
+		return err == nil && !*value
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestRedisStorage_Resubscribe(t *testing.T) {
+	server := miniredis.RunT(t)
+	storage := NewRedisStorage(newTestRedisClientAt(t, server.Addr()), RedisOptions{})
+	defer storage.Close()
+
+	cleared := make(chan struct{}, 1)
+	cancel := storage.Watch(func(key string) {
+		if key == "" {
+			select {
+			case cleared <- struct{}{}:
+			default:
+			}
+		}
+	})
+	defer cancel()
+
+	// 连接断开后重新订阅，并通知清空缓存
+	server.Close()
+	require.NoError(t, server.Restart())
+	select {
+	case <-cleared:
+	case <-time.After(5 * time.Second):
+		t.Fatal("cache was not cleared after resubscribing")
+	}
+
+	// 重新订阅后仍能收到变更
+	changed := make(chan string, 1)
+	defer storage.Watch(func(key string) {
+		if key != "" {
+			changed <- key
+		}
+	})()
+	writer := NewRedisStorage(newTestRedisClientAt(t, server.Addr()), RedisOptions{})
+	require.NoError(t, writer.Set("app.name", "demo"))
+	select {
+	case key := <-changed:
+		assert.Equal(t, "app.name", key)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change notification after resubscribing")
+	}
+}
@@ -0,0 +1,91 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// 密钥引用的前缀，格式为 "secret://<resolver>/<ref>"
+const secretScheme = "secret://"
+
+// SecretResolver 根据引用获取密钥的明文，ref 为引用中解析器名称之后的部分（以 "/" 开头），
+// 例如 "secret://file/run/secrets/db_pw" 的 ref 为 "/run/secrets/db_pw"
+type SecretResolver interface {
+	Resolve(ref string) (string, error)
+}
+
+// SecretResolverFunc 将函数适配为 SecretResolver
+type SecretResolverFunc func(ref string) (string, error)
+
+func (f SecretResolverFunc) Resolve(ref string) (string, error) {
+	return f(ref)
+}
+
+var (
+	secretResolverMap   = make(map[string]SecretResolver)
+	secretResolverMutex sync.RWMutex
+)
+
+func init() {
+	RegisterSecretResolver("file", SecretResolverFunc(resolveFileSecret))
+	RegisterSecretResolver("env", SecretResolverFunc(resolveEnvSecret))
+}
+
+// RegisterSecretResolver 注册密钥解析器，读取到 "secret://<name>/..." 形式的值时调用。
+// 内置 file（读取文件内容）和 env（读取环境变量）两种解析器。
+func RegisterSecretResolver(name string, r SecretResolver) {
+	secretResolverMutex.Lock()
+	defer secretResolverMutex.Unlock()
+	secretResolverMap[name] = r
+}
+
+func lookupSecretResolver(name string) (SecretResolver, bool) {
+	secretResolverMutex.RLock()
+	defer secretResolverMutex.RUnlock()
+	r, ok := secretResolverMap[name]
+	return r, ok
+}
+
+// isSecretRef 判断值是否为密钥引用
+func isSecretRef(value string) bool {
+	return strings.HasPrefix(value, secretScheme)
+}
+
+// resolveSecret 使用注册的解析器获取密钥引用对应的明文
+func resolveSecret(value string) (string, error) {
+	rest := strings.TrimPrefix(value, secretScheme)
+	name, ref, _ := strings.Cut(rest, "/")
+	ref = "/" + ref
+
+	r, ok := lookupSecretResolver(name)
+	if !ok {
+		return "", fmt.Errorf("%w: unknown resolver %q", ErrSecretResolution, name)
+	}
+	secret, err := r.Resolve(ref)
+	if err != nil {
+		// 错误信息中只包含解析器名称，不包含引用之外的内容
+		return "", fmt.Errorf("%w: %s: %v", ErrSecretResolution, name, err)
+	}
+	return secret, nil
+}
+
+// resolveFileSecret 读取文件内容作为密钥，去除末尾的换行符
+func resolveFileSecret(ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// resolveEnvSecret 读取环境变量作为密钥
+func resolveEnvSecret(ref string) (string, error) {
+	name := strings.TrimPrefix(ref, "/")
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s not set", name)
+	}
+	return value, nil
+}
@@ -0,0 +1,67 @@
+package conf
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretReferences(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_pw")
+	require.NoError(t, os.WriteFile(path, []byte("s3cret\n"), 0o600))
+	t.Setenv("TEST_API_TOKEN", "tok-123")
+
+	manager := newSettingManager(newMockStorage())
+	require.NoError(t, manager.Set("db.password", "secret://file"+path))
+	require.NoError(t, manager.Set("api.token", "secret://env/TEST_API_TOKEN"))
+
+	password, err := getTyped[string](manager, "db.password")
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", *password)
+
+	token, err := getTyped[string](manager, "api.token")
+	require.NoError(t, err)
+	assert.Equal(t, "tok-123", *token)
+
+	// 缓存中只保存引用
+	cached, ok := manager.cache.Get("db.password")
+	require.True(t, ok)
+	assert.Equal(t, "secret://file"+path, cached)
+
+	// 每次读取都重新解析
+	require.NoError(t, os.WriteFile(path, []byte("rotated"), 0o600))
+	password, err = getTyped[string](manager, "db.password")
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", *password)
+}
+
+func TestSecretReferences_Errors(t *testing.T) {
+	manager := newSettingManager(newMockStorage())
+	require.NoError(t, manager.Set("a", "secret://vault/kv/db"))
+	require.NoError(t, manager.Set("b", "secret://env/TEST_MISSING_SECRET"))
+
+	_, err := getTyped[string](manager, "a")
+	assert.ErrorIs(t, err, ErrSecretResolution)
+	_, err = getTyped[string](manager, "b")
+	assert.ErrorIs(t, err, ErrSecretResolution)
+
+	RegisterSecretResolver("vault", SecretResolverFunc(func(ref string) (string, error) {
+		if ref == "/kv/db" {
+			return "42", nil
+		}
+		return "", errors.New("not found")
+	}))
+	defer func() {
+		secretResolverMutex.Lock()
+		delete(secretResolverMap, "vault")
+		secretResolverMutex.Unlock()
+	}()
+
+	value, err := getTyped[int](manager, "a")
+	require.NoError(t, err)
+	assert.Equal(t, 42, *value)
+}
@@ -1,10 +1,11 @@
 package conf
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +17,12 @@ var (
 	ErrKeyNotFound      = errors.New("setting key not found")
 	ErrTypeConversion   = errors.New("type conversion failed")
 	ErrStorageOperation = errors.New("storage operation failed")
+	ErrReadOnlyStorage  = errors.New("storage is read-only")
+	ErrVersionConflict  = errors.New("setting version conflict")
+	ErrDecryption       = errors.New("setting decryption failed")
+	ErrSecretResolution = errors.New("secret resolution failed")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrAudit            = errors.New("audit record failed")
 )
 
 type SettingStorage interface {
@@ -24,6 +31,49 @@ type SettingStorage interface {
 	Delete(key string) error
 }
 
+// SettingLister 由能够枚举键的存储实现，返回以 prefix 开头的所有键（按字典序）
+type SettingLister interface {
+	Keys(prefix string) ([]string, error)
+}
+
+// SettingTx 是事务内可用的存储操作
+type SettingTx interface {
+	Get(key string) (string, error)
+	Set(key, value string) error
+	Delete(key string) error
+}
+
+// SettingTransactor 由支持事务的存储实现，fn 返回错误时事务回滚
+type SettingTransactor interface {
+	Update(fn func(tx SettingTx) error) error
+}
+
+// VersionedStorage 由支持版本号和比较并交换（CAS）的存储实现。
+// 版本号 0 表示键不存在；CompareAndSet 和 CompareAndDelete 在当前版本与 version 不一致时返回 ErrVersionConflict。
+type VersionedStorage interface {
+	GetVersion(key string) (value string, version int64, err error)
+	CompareAndSet(key, value string, version int64) (newVersion int64, err error)
+	CompareAndDelete(key string, version int64) error
+}
+
+// SettingWatcher 由能够感知外部变更的存储实现。
+// Watch 注册回调，在键发生变化时调用，key 为空表示所有键都可能已变化；返回的函数用于取消注册。
+type SettingWatcher interface {
+	Watch(fn func(key string)) (cancel func())
+}
+
+// SettingHistory 由记录修订历史的存储实现，每次写入或删除都会产生一个修订
+type SettingHistory interface {
+	// History 返回键的所有修订，最新的在前
+	History(key string) ([]Revision, error)
+	// Revision 按 ID 返回修订，不存在时返回 ErrKeyNotFound
+	Revision(id int64) (*Revision, error)
+	// GetAt 返回键在 at 时刻的值，当时不存在时返回 ErrKeyNotFound
+	GetAt(key string, at time.Time) (string, error)
+	// RevisionsSince 返回 at 之后产生的所有修订，按时间先后排列
+	RevisionsSince(at time.Time) ([]Revision, error)
+}
+
 // 添加缓存管理器结构体
 type settingCache struct {
 	cache      map[string]string
@@ -112,9 +162,27 @@ func (sc *settingCache) Delete(key string) {
 	delete(sc.cache, key)
 }
 
+// Clear 清空缓存
+func (sc *settingCache) Clear() {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+	sc.cache = make(map[string]string)
+	sc.lastAccess = make(map[string]time.Time)
+}
+
 type SettingManager struct {
-	storage SettingStorage
-	cache   *settingCache
+	storage    SettingStorage
+	cache      *settingCache
+	decodeMode DecodeMode
+	codec      Codec
+	keyCodecs  []keyCodec
+	viper      *viper.Viper
+	viperMode  ViperMode
+	sources    []Source
+	unwatch    []func()
+	sensitive  []string
+	authorizer Authorizer
+	auditSink  AuditSink
 }
 
 var (
@@ -125,51 +193,110 @@ var (
 // NewSettingManager 创建设置管理器
 func NewSettingManager(storage SettingStorage) *SettingManager {
 	_initOnce.Do(func() {
-		_settingsManager = &SettingManager{
-			storage: storage,
-			cache: &settingCache{
-				cache:      make(map[string]string),
-				lastAccess: make(map[string]time.Time),
-				maxSize:    1000,
-				expiration: 1 * time.Hour,
-			},
-		}
+		_settingsManager = newSettingManager(storage)
 	})
 	return _settingsManager
 }
 
+// NewManager 创建一个独立的设置管理器，与 NewSettingManager 的全局实例和包级函数（Get、Set 等）互不影响，
+// 适合同时操作多个存储的程序，类型化读取使用 GetFrom
+func NewManager(storage SettingStorage) *SettingManager {
+	return newSettingManager(storage)
+}
+
+// newSettingManager 创建一个独立的设置管理器实例
+func newSettingManager(storage SettingStorage) *SettingManager {
+	sm := &SettingManager{
+		storage: storage,
+		cache: &settingCache{
+			cache:      make(map[string]string),
+			lastAccess: make(map[string]time.Time),
+			maxSize:    1000,
+			expiration: 1 * time.Hour,
+		},
+	}
+	sm.watchStorages()
+	return sm
+}
+
 // SetStorage 设置设置存储器
 func (sm *SettingManager) SetStorage(storage SettingStorage) {
 	sm.storage = storage
+	sm.cache.Clear()
+	sm.watchStorages()
+}
+
+// watchStorages 订阅存储层的变更通知，外部修改时使对应的缓存失效
+func (sm *SettingManager) watchStorages() {
+	for _, cancel := range sm.unwatch {
+		cancel()
+	}
+	sm.unwatch = nil
+
+	storages := []SettingStorage{sm.storage}
+	for _, source := range sm.sources {
+		if s, ok := source.(*storageSource); ok {
+			storages = append(storages, s.storage)
+		}
+	}
+	for _, storage := range storages {
+		if watcher, ok := storage.(SettingWatcher); ok {
+			sm.unwatch = append(sm.unwatch, watcher.Watch(sm.invalidate))
+		}
+	}
+}
+
+// invalidate 使指定键的缓存失效，key 为空时清空缓存
+func (sm *SettingManager) invalidate(key string) {
+	if key == "" {
+		sm.cache.Clear()
+		return
+	}
+	sm.cache.Delete(key)
 }
 
 // Set 设置设置
 func (sm *SettingManager) Set(key string, value any) (err error) {
-	var strValue string
+	return sm.set(context.Background(), key, value)
+}
+
+// set 检查写入权限后写入配置，ctx 提供主体以及审计记录中的操作者和原因
+func (sm *SettingManager) set(ctx context.Context, key string, value any) error {
+	if err := sm.authorize(ctx, ActionWrite, key); err != nil {
+		return err
+	}
+	strValue, err := sm.encodeValue(key, value)
+	if err != nil {
+		return err
+	}
+	if err := sm.audit(ctx, AuditSet, key, strValue); err != nil {
+		return err
+	}
+	if sm.sources != nil {
+		// 存储层之上可能还有更高优先级的来源层，只能让缓存失效
+		sm.cache.Delete(key)
+	} else {
+		sm.cache.Set(key, strValue)
+	}
+	return sm.storage.Set(key, strValue)
+}
+
+// encodeValue 将配置值编码为存储使用的字符串
+func (sm *SettingManager) encodeValue(key string, value any) (string, error) {
 	switch v := any(value).(type) {
 	case string:
-		strValue = v
+		return v, nil
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
-		strValue = fmt.Sprintf("%d", v)
+		return fmt.Sprintf("%d", v), nil
 	case float32:
-		strValue = strconv.FormatFloat(float64(v), 'g', -1, 32)
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
 	case float64:
-		strValue = strconv.FormatFloat(v, 'g', -1, 64)
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
 	case bool:
-		strValue = fmt.Sprintf("%t", v)
+		return fmt.Sprintf("%t", v), nil
 	default:
-		bytes, err := json.Marshal(value)
-		if err != nil {
-			return err
-		}
-		strValue = string(bytes)
+		return encodeWithCodec(sm.codecFor(key), value)
 	}
-	sm.cache.Set(key, strValue)
-	err = sm.storage.Set(key, strValue)
-	if err != nil {
-		return err
-	}
-	return nil
 }
 
 // Set stores a setting with the given key and value
@@ -183,17 +310,20 @@ func (sm *SettingManager) Get(key string) (any, error) {
 		return value, nil
 	}
 
+	if sm.sources != nil {
+		return sm.getFromSources(key)
+	}
+
 	// 从存储层获取
 	value, err := sm.storage.Get(key)
 	if err != nil {
-		if err == ErrKeyNotFound {
+		if errors.Is(err, ErrKeyNotFound) {
 			// 尝试从 viper 获取
-			if viper.IsSet(key) {
-				result := viper.Get(key)
-				// 找到值后保存到存储层
-				if err := sm.Set(key, result); err != nil {
-					return nil, err
-				}
+			result, ok, viperErr := sm.lookupViper(key)
+			if viperErr != nil {
+				return nil, viperErr
+			}
+			if ok {
 				return result, nil
 			}
 		}
@@ -221,15 +351,24 @@ func Get[T any](key string) (*T, error) {
 	if _settingsManager == nil {
 		return nil, fmt.Errorf("settings manager not initialized")
 	}
+	return getTyped[T](_settingsManager, key)
+}
+
+// GetFrom retrieves a setting from the given manager and converts it to T
+func GetFrom[T any](sm *SettingManager, key string) (*T, error) {
+	return getTyped[T](sm, key)
+}
 
-	value, err := _settingsManager.Get(key)
+// getTyped 从指定的管理器读取配置并转换为目标类型
+func getTyped[T any](sm *SettingManager, key string) (*T, error) {
+	value, err := sm.Get(key)
 	if err != nil {
 		return nil, err
 	}
 
 	// 如果值是字符串，尝试解析
 	if strValue, ok := value.(string); ok {
-		return parseValue[T](strValue)
+		return parseStored[T](sm, key, strValue)
 	}
 
 	// 如果类型已经匹配，直接返回
@@ -237,21 +376,41 @@ func Get[T any](key string) (*T, error) {
 		return &typed, nil
 	}
 
-	// 尝试通过 JSON 转换
-	jsonData, err := json.Marshal(value)
-	if err != nil {
-		return nil, fmt.Errorf("marshal error: %w", err)
-	}
+	result, err := convertValue[T](value, sm.decodeMode)
+	return result, sm.redactError(key, fmt.Sprint(value), err)
+}
 
-	var result T
-	if err := json.Unmarshal(jsonData, &result); err != nil {
-		return nil, fmt.Errorf("unmarshal error: %w", err)
+// parseStored 解析存储中的字符串值：先解析密钥引用，再按解码模式转换为目标类型
+func parseStored[T any](sm *SettingManager, key, value string) (*T, error) {
+	// 密钥引用在读取时解析，明文不进入缓存
+	if isSecretRef(value) {
+		secret, err := resolveSecret(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		// 解析器返回的明文无论键是否被标记为敏感都不能出现在错误信息中
+		result, err := parseValueMode[T](secret, sm.decodeMode)
+		if err != nil {
+			return nil, &redactedError{err: err, value: secret}
+		}
+		return result, nil
 	}
-
-	return &result, nil
+	result, err := parseValueMode[T](value, sm.decodeMode)
+	return result, sm.redactError(key, value, err)
 }
 
 func (sm *SettingManager) Delete(key string) error {
+	return sm.delete(context.Background(), key)
+}
+
+// delete 检查删除权限后删除配置，ctx 提供主体以及审计记录中的操作者和原因
+func (sm *SettingManager) delete(ctx context.Context, key string) error {
+	if err := sm.authorize(ctx, ActionDelete, key); err != nil {
+		return err
+	}
+	if err := sm.audit(ctx, AuditDelete, key, ""); err != nil {
+		return err
+	}
 	sm.cache.Delete(key)
 	return sm.storage.Delete(key)
 }
@@ -284,11 +443,30 @@ func registerParser[T any](parser TypeParser[T]) {
 	typeParserMap[fmt.Sprintf("%T", *t)] = parser
 }
 
-// parseValue 优化后的实现
+// parseValue 使用默认解码模式解析字符串值
 func parseValue[T any](value string) (*T, error) {
+	return parseValueMode[T](value, DecodeDefault)
+}
+
+// parseValueMode 按指定的解码模式解析字符串值
+func parseValueMode[T any](value string, mode DecodeMode) (*T, error) {
 	var result T
 	resultType := fmt.Sprintf("%T", result)
 
+	// 宽松模式下先去除首尾空白，并优先使用宽松解析器
+	if mode == DecodeLenient {
+		value = strings.TrimSpace(value)
+		if parser, ok := lenientParserMap[resultType]; ok {
+			if typedParser, ok := parser.(TypeParser[T]); ok {
+				parsed, err := typedParser(value)
+				if err != nil {
+					return nil, fmt.Errorf("parse %s error: %w", resultType, err)
+				}
+				return &parsed, nil
+			}
+		}
+	}
+
 	// 尝试使用注册的解析器
 	if parser, ok := typeParserMap[resultType]; ok {
 		if typedParser, ok := parser.(TypeParser[T]); ok {
@@ -300,8 +478,8 @@ func parseValue[T any](value string) (*T, error) {
 		}
 	}
 
-	// 对于复杂类型，使用 JSON 解析
-	if err := json.Unmarshal([]byte(value), &result); err != nil {
+	// 对于复杂类型，按记录的编解码器解析（默认为 JSON）
+	if err := decodeComplex(value, &result, mode); err != nil {
 		return nil, fmt.Errorf("unmarshal complex type error: %w", err)
 	}
 	return &result, nil
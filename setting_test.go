@@ -413,3 +413,16 @@ func FuzzSettingManager_Set(f *testing.F) {
 		}
 	})
 }
+
+func TestNewManager_Independent(t *testing.T) {
+	first, second := NewManager(newMockStorage()), NewManager(newMockStorage())
+	require.NotSame(t, first, second)
+
+	require.NoError(t, first.Set("app.port", 8080))
+	port, err := GetFrom[int](first, "app.port")
+	require.NoError(t, err)
+	assert.Equal(t, 8080, *port)
+
+	_, err = GetFrom[int](second, "app.port")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+}
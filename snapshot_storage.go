@@ -0,0 +1,300 @@
+package conf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SnapshotOptions 快照存储的配置
+type SnapshotOptions struct {
+	// Path 快照文件路径
+	Path string
+	// Interval 定期保存快照的间隔，默认为 1 分钟，小于 0 时不定期保存
+	Interval time.Duration
+	// OnError 接收定期刷新快照时的错误
+	OnError func(err error)
+}
+
+// SnapshotStorage 包装主存储，定期将最后一次成功读取到的配置保存到本地文件。
+// 主存储出错时从快照提供配置并标记为过期（Stale），使服务在数据库故障期间仍能启动。
+// 主存储恢复后会通知所有配置已变化，使管理器丢弃缓存中的过期值。
+type SnapshotStorage struct {
+	primary  SettingStorage
+	opts     SnapshotOptions
+	notifier changeNotifier
+
+	mutex   sync.RWMutex
+	values  map[string]string
+	savedAt time.Time
+	stale   bool
+	dirty   bool
+
+	unwatch   func()
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// snapshotFile 是快照文件的内容
+type snapshotFile struct {
+	SavedAt time.Time         `json:"saved_at"`
+	Values  map[string]string `json:"values"`
+}
+
+// NewSnapshotStorage 创建快照存储，快照文件存在时先加载其中的配置。
+// 主存储实现 SettingLister 时每次刷新都会保存全部配置，否则只保存经由本存储读写过的配置。
+// 使用完毕后应调用 Close 保存最后一次快照。
+func NewSnapshotStorage(primary SettingStorage, opts SnapshotOptions) (*SnapshotStorage, error) {
+	if opts.Path == "" {
+		return nil, fmt.Errorf("%w: snapshot path is required", ErrStorageOperation)
+	}
+	if opts.Interval == 0 {
+		opts.Interval = time.Minute
+	}
+
+	ss := &SnapshotStorage{
+		primary: primary,
+		opts:    opts,
+		values:  make(map[string]string),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+	if err := ss.load(); err != nil {
+		return nil, err
+	}
+	if watcher, ok := primary.(SettingWatcher); ok {
+		ss.unwatch = watcher.Watch(ss.notifier.notify)
+	}
+
+	if opts.Interval > 0 {
+		go ss.refreshLoop()
+	} else {
+		close(ss.done)
+	}
+	return ss, nil
+}
+
+// load 读取快照文件，文件不存在时保持为空
+func (ss *SnapshotStorage) load() error {
+	data, err := os.ReadFile(ss.opts.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+
+	var file snapshotFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("%w: parse snapshot %s: %v", ErrStorageOperation, ss.opts.Path, err)
+	}
+	if file.Values != nil {
+		ss.values = file.Values
+	}
+	ss.savedAt = file.SavedAt
+	return nil
+}
+
+func (ss *SnapshotStorage) Get(key string) (string, error) {
+	value, err := ss.primary.Get(key)
+	if err == nil || errors.Is(err, ErrKeyNotFound) {
+		ss.recordFresh(key, value, err == nil)
+		return value, err
+	}
+
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	ss.stale = true
+	if value, ok := ss.values[key]; ok {
+		return value, nil
+	}
+	return "", err
+}
+
+func (ss *SnapshotStorage) Set(key, value string) error {
+	if err := ss.primary.Set(key, value); err != nil {
+		return err
+	}
+	ss.recordFresh(key, value, true)
+	return nil
+}
+
+func (ss *SnapshotStorage) Delete(key string) error {
+	if err := ss.primary.Delete(key); err != nil {
+		return err
+	}
+	ss.recordFresh(key, "", false)
+	return nil
+}
+
+// recordFresh 记录主存储返回的结果，exists 为 false 表示键不存在
+func (ss *SnapshotStorage) recordFresh(key, value string, exists bool) {
+	ss.mutex.Lock()
+	recovered := ss.stale
+	ss.stale = false
+	if old, ok := ss.values[key]; exists && (!ok || old != value) {
+		ss.values[key] = value
+		ss.dirty = true
+	} else if !exists && ok {
+		delete(ss.values, key)
+		ss.dirty = true
+	}
+	ss.mutex.Unlock()
+
+	// 故障期间提供的值可能已过期
+	if recovered {
+		ss.notifier.notify("")
+	}
+}
+
+// Stale 返回最近一次访问主存储是否失败，为 true 时读取到的值来自快照
+func (ss *SnapshotStorage) Stale() bool {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+	return ss.stale
+}
+
+// SnapshotTime 返回快照最后一次保存的时间，从未保存过时为零值
+func (ss *SnapshotStorage) SnapshotTime() time.Time {
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+	return ss.savedAt
+}
+
+// Keys 返回以 prefix 开头的所有键，主存储不可用时从快照列出
+func (ss *SnapshotStorage) Keys(prefix string) ([]string, error) {
+	if lister, ok := ss.primary.(SettingLister); ok {
+		keys, err := lister.Keys(prefix)
+		if err == nil {
+			return keys, nil
+		}
+	}
+
+	ss.mutex.RLock()
+	defer ss.mutex.RUnlock()
+
+	var keys []string
+	for k := range ss.values {
+		if strings.HasPrefix(k, prefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Watch 注册变更回调，转发主存储的变更通知
+func (ss *SnapshotStorage) Watch(fn func(key string)) (cancel func()) {
+	return ss.notifier.Watch(fn)
+}
+
+// Refresh 从主存储重新读取配置并保存快照：主存储支持列举时读取全部配置，
+// 否则重新读取快照中已有的键。主存储不可用时保留已有的快照并返回错误。
+func (ss *SnapshotStorage) Refresh() error {
+	values, err := ss.readAll()
+
+	ss.mutex.Lock()
+	recovered := ss.stale && err == nil
+	ss.stale = err != nil
+	if err == nil {
+		ss.values = values
+		ss.dirty = true
+	}
+	ss.mutex.Unlock()
+
+	if recovered {
+		ss.notifier.notify("")
+	}
+	if err != nil {
+		return err
+	}
+	return ss.save()
+}
+
+// readAll 从主存储读取需要保存到快照中的配置
+func (ss *SnapshotStorage) readAll() (map[string]string, error) {
+	var keys []string
+	if lister, ok := ss.primary.(SettingLister); ok {
+		var err error
+		if keys, err = lister.Keys(""); err != nil {
+			return nil, err
+		}
+	} else {
+		ss.mutex.RLock()
+		for k := range ss.values {
+			keys = append(keys, k)
+		}
+		ss.mutex.RUnlock()
+	}
+
+	values := make(map[string]string, len(keys))
+	for _, key := range keys {
+		value, err := ss.primary.Get(key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// save 在快照有变化时写入文件
+func (ss *SnapshotStorage) save() error {
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	if !ss.dirty {
+		return nil
+	}
+	file := snapshotFile{SavedAt: time.Now(), Values: ss.values}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	if err := writeFileAtomic(ss.opts.Path, data); err != nil {
+		return fmt.Errorf("%w: %v", ErrStorageOperation, err)
+	}
+	ss.savedAt = file.SavedAt
+	ss.dirty = false
+	return nil
+}
+
+func (ss *SnapshotStorage) refreshLoop() {
+	defer close(ss.done)
+
+	ticker := time.NewTicker(ss.opts.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ss.stop:
+			return
+		case <-ticker.C:
+			if err := ss.Refresh(); err != nil && ss.opts.OnError != nil {
+				ss.opts.OnError(err)
+			}
+		}
+	}
+}
+
+// Close 停止定期刷新并保存最后一次快照，不会关闭主存储
+func (ss *SnapshotStorage) Close() error {
+	ss.closeOnce.Do(func() {
+		close(ss.stop)
+		if ss.unwatch != nil {
+			ss.unwatch()
+		}
+	})
+	<-ss.done
+	return ss.save()
+}
@@ -0,0 +1,101 @@
+package conf
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyStorage 可切换为不可用状态的存储
+type flakyStorage struct {
+	*FileStorage
+	down atomic.Bool
+}
+
+func (fs *flakyStorage) Get(key string) (string, error) {
+	if fs.down.Load() {
+		return unavailableStorage{}.Get(key)
+	}
+	return fs.FileStorage.Get(key)
+}
+
+func (fs *flakyStorage) Keys(prefix string) ([]string, error) {
+	if fs.down.Load() {
+		_, err := unavailableStorage{}.Get(prefix)
+		return nil, err
+	}
+	return fs.FileStorage.Keys(prefix)
+}
+
+func newFlakyStorage(t *testing.T) *flakyStorage {
+	t.Helper()
+
+	storage, err := NewFileStorage(filepath.Join(t.TempDir(), "settings.json"), FormatJSON)
+	require.NoError(t, err)
+	return &flakyStorage{FileStorage: storage}
+}
+
+func TestSnapshotStorage_ServesStaleValues(t *testing.T) {
+	primary := newFlakyStorage(t)
+	require.NoError(t, primary.Set("app.name", "demo"))
+	require.NoError(t, primary.Set("app.port", "8080"))
+
+	path := filepath.Join(t.TempDir(), "snapshot.json")
+	storage, err := NewSnapshotStorage(primary, SnapshotOptions{Path: path, Interval: -1})
+	require.NoError(t, err)
+	require.NoError(t, storage.Refresh())
+	assert.False(t, storage.SnapshotTime().IsZero())
+	require.NoError(t, storage.Close())
+
+	// 数据库故障期间重新启动
+	primary.down.Store(true)
+	storage, err = NewSnapshotStorage(primary, SnapshotOptions{Path: path, Interval: -1})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	value, err := storage.Get("app.port")
+	require.NoError(t, err)
+	assert.Equal(t, "8080", value)
+	assert.True(t, storage.Stale())
+
+	_, err = storage.Get("missing")
+	assert.ErrorIs(t, err, ErrStorageOperation)
+
+	keys, err := storage.Keys("app.")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.name", "app.port"}, keys)
+
+	assert.ErrorIs(t, storage.Refresh(), ErrStorageOperation)
+}
+
+func TestSnapshotStorage_Recovery(t *testing.T) {
+	primary := newFlakyStorage(t)
+	require.NoError(t, primary.Set("app.port", "8080"))
+
+	storage, err := NewSnapshotStorage(primary, SnapshotOptions{
+		Path:     filepath.Join(t.TempDir(), "snapshot.json"),
+		Interval: 10 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer storage.Close()
+
+	manager := newSettingManager(storage)
+	port, err := getTyped[int](manager, "app.port")
+	require.NoError(t, err)
+	assert.Equal(t, 8080, *port)
+
+	// 故障期间修改了配置，恢复后缓存应失效
+	primary.down.Store(true)
+	require.NoError(t, primary.FileStorage.Set("app.port", "9090"))
+	assert.Eventually(t, storage.Stale, time.Second, 5*time.Millisecond)
+	primary.down.Store(false)
+
+	assert.Eventually(t, func() bool {
+		port, err := getTyped[int](manager, "app.port")
+		return err == nil && *port == 9090 && !storage.Stale()
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,188 @@
+package conf
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Source 是配置值的一个来源层，例如命令行参数、环境变量、数据库、配置文件或默认值。
+// 键不存在时 Get 应返回 ErrKeyNotFound。
+type Source interface {
+	Name() string
+	Get(key string) (any, error)
+}
+
+// LayerValue 记录某个来源层提供的值
+type LayerValue struct {
+	Source string
+	Value  any
+}
+
+// Explanation 描述一个键的生效值来自哪个来源层，以及被覆盖的来源层
+type Explanation struct {
+	Key      string
+	Value    any
+	Source   string
+	Shadowed []LayerValue
+}
+
+// SetSources 设置按优先级从高到低排列的来源层，替代默认的 存储层 → viper 回退。
+// Set 和 Delete 始终作用于管理器的存储层，存储层应通过 NewStorageSource 加入来源列表。
+func (sm *SettingManager) SetSources(sources ...Source) {
+	sm.sources = sources
+	sm.cache.Clear()
+	sm.watchStorages()
+}
+
+// Sources 返回当前生效的来源层列表
+func (sm *SettingManager) Sources() []Source {
+	if sm.sources != nil {
+		return sm.sources
+	}
+
+	sources := []Source{NewStorageSource("storage", sm.storage)}
+	if sm.viperMode != ViperDisabled {
+		sources = append(sources, NewViperSource("viper", sm.viperInstance()))
+	}
+	return sources
+}
+
+// Explain 说明键的生效值来自哪个来源层，以及哪些来源层的值被覆盖，敏感键的值会被隐藏
+func (sm *SettingManager) Explain(key string) (*Explanation, error) {
+	var explanation *Explanation
+	for _, source := range sm.Sources() {
+		value, err := source.Get(key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if explanation == nil {
+			explanation = &Explanation{Key: key, Value: sm.Redact(key, value), Source: source.Name()}
+			continue
+		}
+		explanation.Shadowed = append(explanation.Shadowed, LayerValue{Source: source.Name(), Value: sm.Redact(key, value)})
+	}
+
+	if explanation == nil {
+		return nil, ErrKeyNotFound
+	}
+	return explanation, nil
+}
+
+// getFromSources 按优先级依次查询来源层
+func (sm *SettingManager) getFromSources(key string) (any, error) {
+	for _, source := range sm.sources {
+		value, err := source.Get(key)
+		if errors.Is(err, ErrKeyNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// 只缓存管理器存储层的值：Set/Delete 只会使存储层的缓存失效，
+		// 缓存其他层（例如环境变量）的值会掩盖之后存储层中的变化
+		if str, ok := value.(string); ok {
+			if s, isStorage := source.(*storageSource); isStorage && sameStorage(s.storage, sm.storage) {
+				sm.cache.Set(key, str)
+			}
+		}
+		return value, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+type storageSource struct {
+	name    string
+	storage SettingStorage
+}
+
+// NewStorageSource 将 SettingStorage 包装为来源层。
+// 来源层只用于读取，写入始终作用于管理器的存储层。
+func NewStorageSource(name string, storage SettingStorage) Source {
+	return &storageSource{name: name, storage: storage}
+}
+
+func (s *storageSource) Name() string                { return s.name }
+func (s *storageSource) Get(key string) (any, error) { return s.storage.Get(key) }
+
+// sameStorage 判断两个存储是否为同一个实例，不可比较的类型视为不同
+func sameStorage(a, b SettingStorage) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	return ta == tb && ta.Comparable() && a == b
+}
+
+type viperSource struct {
+	name string
+	v    *viper.Viper
+}
+
+// NewViperSource 将 viper 实例包装为只读来源层，v 为 nil 时使用全局 viper
+func NewViperSource(name string, v *viper.Viper) Source {
+	return &viperSource{name: name, v: v}
+}
+
+func (s *viperSource) Name() string { return s.name }
+
+func (s *viperSource) Get(key string) (any, error) {
+	v := s.v
+	if v == nil {
+		v = viper.GetViper()
+	}
+	if !v.IsSet(key) {
+		return nil, ErrKeyNotFound
+	}
+	return v.Get(key), nil
+}
+
+type mapSource struct {
+	name   string
+	values map[string]any
+}
+
+// NewMapSource 使用固定的键值创建只读来源层，通常用于默认值
+func NewMapSource(name string, values map[string]any) Source {
+	return &mapSource{name: name, values: values}
+}
+
+func (s *mapSource) Name() string { return s.name }
+
+func (s *mapSource) Get(key string) (any, error) {
+	value, ok := s.values[key]
+	if !ok {
+		return nil, ErrKeyNotFound
+	}
+	return value, nil
+}
+
+type flagSource struct {
+	name  string
+	flags *pflag.FlagSet
+}
+
+// NewFlagSource 将命令行参数包装为只读来源层。
+// 只有显式传入的参数才会生效，键 "app.port" 对应参数 --app.port 或 --app-port。
+func NewFlagSource(name string, flags *pflag.FlagSet) Source {
+	return &flagSource{name: name, flags: flags}
+}
+
+func (s *flagSource) Name() string { return s.name }
+
+func (s *flagSource) Get(key string) (any, error) {
+	for _, name := range []string{key, strings.ReplaceAll(key, ".", "-")} {
+		if f := s.flags.Lookup(name); f != nil && f.Changed {
+			return f.Value.String(), nil
+		}
+	}
+	return nil, ErrKeyNotFound
+}
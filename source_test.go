@@ -0,0 +1,100 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingManager_Sources(t *testing.T) {
+	storage := newMockStorage()
+	manager := newSettingManager(storage)
+
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.Int("app-port", 0, "")
+	require.NoError(t, flags.Parse([]string{"--app-port=9000"}))
+
+	file := viper.New()
+	file.Set("app.port", 8000)
+	file.Set("app.name", "from-file")
+
+	manager.SetSources(
+		NewFlagSource("flags", flags),
+		NewMapSource("env", map[string]any{"app.debug": "true"}),
+		NewStorageSource("database", storage),
+		NewViperSource("file", file),
+		NewMapSource("defaults", map[string]any{"app.port": 80, "app.timeout": "30s"}),
+	)
+
+	require.NoError(t, manager.Set("app.port", 8500))
+	require.NoError(t, manager.Set("app.name", "from-database"))
+
+	port, err := getTyped[int](manager, "app.port")
+	require.NoError(t, err)
+	assert.Equal(t, 9000, *port)
+
+	name, err := getTyped[string](manager, "app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "from-database", *name)
+
+	debug, err := getTyped[bool](manager, "app.debug")
+	require.NoError(t, err)
+	assert.True(t, *debug)
+
+	timeout, err := getTyped[string](manager, "app.timeout")
+	require.NoError(t, err)
+	assert.Equal(t, "30s", *timeout)
+
+	_, err = manager.Get("app.missing")
+	assert.ErrorIs(t, err, ErrKeyNotFound)
+
+	t.Run("explain", func(t *testing.T) {
+		explanation, err := manager.Explain("app.port")
+		require.NoError(t, err)
+		assert.Equal(t, "flags", explanation.Source)
+		assert.Equal(t, "9000", explanation.Value)
+		assert.Equal(t, []LayerValue{
+			{Source: "database", Value: "8500"},
+			{Source: "file", Value: 8000},
+			{Source: "defaults", Value: 80},
+		}, explanation.Shadowed)
+
+		_, err = manager.Explain("app.missing")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+}
+
+func TestSettingManager_DefaultSources(t *testing.T) {
+	storage := newMockStorage()
+	v := viper.New()
+	v.Set("app.name", "from-file")
+
+	manager := newSettingManager(storage)
+	manager.SetViper(v)
+	require.NoError(t, manager.Set("app.name", "from-storage"))
+
+	explanation, err := manager.Explain("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "storage", explanation.Source)
+	assert.Equal(t, []LayerValue{{Source: "viper", Value: "from-file"}}, explanation.Shadowed)
+}
+
+func TestSettingManager_SourcesCacheOnlyStorage(t *testing.T) {
+	storage, fallback := newMockStorage(), newMockStorage()
+	manager := newSettingManager(storage)
+	manager.SetSources(NewStorageSource("database", storage), NewStorageSource("fallback", fallback))
+
+	require.NoError(t, fallback.Set("app.name", "from-fallback"))
+	value, err := manager.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "from-fallback", value)
+
+	// 存储层之后出现的值不会被缓存的低优先级值掩盖
+	require.NoError(t, storage.Set("app.name", "from-database"))
+	value, err = manager.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "from-database", value)
+}
@@ -0,0 +1,32 @@
+// Package sqlite 提供基于 SQLite 的配置存储。
+//
+// SQLite 驱动依赖 cgo（mattn/go-sqlite3），因此放在单独的包中，
+// 只有导入本包的程序才需要 cgo，conf 包本身仍可在 CGO_ENABLED=0 下构建。
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/hope183/conf"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open 打开 path 处的 SQLite 数据库，文件不存在时会被创建
+func Open(path string) (*gorm.DB, error) {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", conf.ErrStorageOperation, err)
+	}
+	return db, nil
+}
+
+// NewStorage 打开 path 处的 SQLite 数据库并创建存储
+func NewStorage(path string) (*conf.GormStorage, error) {
+	db, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return conf.NewGormStorage(db)
+}
@@ -0,0 +1,27 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/hope183/conf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStorage(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.db")
+	storage, err := NewStorage(path)
+	require.NoError(t, err)
+	require.NoError(t, storage.Set("app.name", "demo"))
+
+	// 重新打开后数据仍然存在
+	reopened, err := NewStorage(path)
+	require.NoError(t, err)
+	value, err := reopened.Get("app.name")
+	require.NoError(t, err)
+	assert.Equal(t, "demo", value)
+
+	_, err = reopened.Get("app.missing")
+	assert.ErrorIs(t, err, conf.ErrKeyNotFound)
+}
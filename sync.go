@@ -0,0 +1,126 @@
+package conf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/spf13/viper"
+)
+
+// SyncPolicy 控制从 viper 导入配置时如何处理存储层中已有的键
+type SyncPolicy int
+
+const (
+	// SyncMissing 只导入存储层中不存在的键
+	SyncMissing SyncPolicy = iota
+	// SyncOverwrite 导入所有键，覆盖存储层中不同的值
+	SyncOverwrite
+	// SyncDryRun 只计算 SyncOverwrite 会产生的差异，不写入存储层
+	SyncDryRun
+)
+
+// String 返回策略名称
+func (p SyncPolicy) String() string {
+	switch p {
+	case SyncMissing:
+		return "missing"
+	case SyncOverwrite:
+		return "overwrite"
+	case SyncDryRun:
+		return "dry-run"
+	default:
+		return fmt.Sprintf("SyncPolicy(%d)", int(p))
+	}
+}
+
+// SyncChange 记录一个键在同步中的变化，敏感键的值会被隐藏
+type SyncChange struct {
+	Key      string `json:"key"`
+	OldValue string `json:"old_value,omitempty"`
+	NewValue string `json:"new_value,omitempty"`
+}
+
+// SyncResult 汇总一次同步的结果
+type SyncResult struct {
+	// Added 存储层中原本不存在的键
+	Added []SyncChange
+	// Changed 存储层中值不同的键；SyncMissing 策略下这些键不会被写入
+	Changed []SyncChange
+	// Unchanged 值相同的键
+	Unchanged []string
+	// Applied 是否实际写入了存储层；返回错误时只有在已经写入了部分键时才为 true
+	Applied bool
+}
+
+// SyncFromViper 将 viper 实例中的所有键导入存储层，用于从已有配置文件初始化数据库
+func (sm *SettingManager) SyncFromViper(v *viper.Viper, policy SyncPolicy) (*SyncResult, error) {
+	return sm.SyncFromViperContext(context.Background(), v, policy)
+}
+
+// SyncFromViperContext 与 SyncFromViper 相同，ctx 提供授权检查使用的主体。
+// 设置了授权器时，在写入任何键之前检查所有待写入键的权限。
+// 返回的结果总是非 nil：出错时结果包含出错之前已经比较过的键，Applied 表示是否已经写入了部分键。
+func (sm *SettingManager) SyncFromViperContext(ctx context.Context, v *viper.Viper, policy SyncPolicy) (*SyncResult, error) {
+	if v == nil {
+		v = viper.GetViper()
+	}
+
+	keys := v.AllKeys()
+	sort.Strings(keys)
+
+	result := &SyncResult{}
+	var writes []string
+	for _, key := range keys {
+		newValue, err := sm.encodeValue(key, v.Get(key))
+		if err != nil {
+			return result, fmt.Errorf("encode %s error: %w", key, err)
+		}
+
+		oldValue, err := sm.storage.Get(key)
+		switch {
+		case errors.Is(err, ErrKeyNotFound):
+			result.Added = append(result.Added, SyncChange{Key: key, NewValue: sm.redactString(key, newValue)})
+			if policy == SyncDryRun {
+				continue
+			}
+		case err != nil:
+			return result, fmt.Errorf("read %s: %w", key, err)
+		case oldValue == newValue:
+			result.Unchanged = append(result.Unchanged, key)
+			continue
+		default:
+			result.Changed = append(result.Changed, SyncChange{
+				Key:      key,
+				OldValue: sm.redactString(key, oldValue),
+				NewValue: sm.redactString(key, newValue),
+			})
+			if policy != SyncOverwrite {
+				continue
+			}
+		}
+
+		writes = append(writes, key)
+	}
+
+	if err := sm.authorizeWrites(ctx, writes, nil); err != nil {
+		return result, err
+	}
+	for i, key := range writes {
+		if err := sm.set(ctx, key, v.Get(key)); err != nil {
+			result.Applied = i > 0
+			return result, fmt.Errorf("write %s: %w", key, err)
+		}
+	}
+	result.Applied = policy != SyncDryRun
+	return result, nil
+}
+
+// SyncFromViper imports all keys of a viper instance into the global manager's storage
+func SyncFromViper(v *viper.Viper, policy SyncPolicy) (*SyncResult, error) {
+	if _settingsManager == nil {
+		return nil, fmt.Errorf("settings manager not initialized")
+	}
+	return _settingsManager.SyncFromViper(v, policy)
+}
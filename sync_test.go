@@ -0,0 +1,122 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSyncTestViper(t *testing.T) *viper.Viper {
+	t.Helper()
+
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader(`
+app:
+  name: demo
+  port: 8080
+  debug: true
+`)))
+	return v
+}
+
+func TestSettingManager_SyncFromViper(t *testing.T) {
+	t.Run("only missing", func(t *testing.T) {
+		storage := newMockStorage()
+		storage.data["app.port"] = "9090"
+		storage.data["app.debug"] = "true"
+		manager := newSettingManager(storage)
+
+		result, err := manager.SyncFromViper(newSyncTestViper(t), SyncMissing)
+		require.NoError(t, err)
+
+		assert.True(t, result.Applied)
+		assert.Equal(t, []SyncChange{{Key: "app.name", NewValue: "demo"}}, result.Added)
+		assert.Equal(t, []SyncChange{{Key: "app.port", OldValue: "9090", NewValue: "8080"}}, result.Changed)
+		assert.Equal(t, []string{"app.debug"}, result.Unchanged)
+
+		assert.Equal(t, "demo", storage.data["app.name"])
+		assert.Equal(t, "9090", storage.data["app.port"])
+	})
+
+	t.Run("overwrite", func(t *testing.T) {
+		storage := newMockStorage()
+		storage.data["app.port"] = "9090"
+		manager := newSettingManager(storage)
+
+		_, err := manager.SyncFromViper(newSyncTestViper(t), SyncOverwrite)
+		require.NoError(t, err)
+
+		assert.Equal(t, "8080", storage.data["app.port"])
+		got, err := getTyped[int](manager, "app.port")
+		require.NoError(t, err)
+		assert.Equal(t, 8080, *got)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		storage := newMockStorage()
+		storage.data["app.port"] = "9090"
+		manager := newSettingManager(storage)
+
+		result, err := manager.SyncFromViper(newSyncTestViper(t), SyncDryRun)
+		require.NoError(t, err)
+
+		assert.False(t, result.Applied)
+		assert.Len(t, result.Added, 2)
+		assert.Len(t, result.Changed, 1)
+		assert.Equal(t, map[string]string{"app.port": "9090"}, storage.data)
+	})
+
+	t.Run("read-only storage", func(t *testing.T) {
+		storage, err := NewEnvStorage(EnvOptions{Prefix: "CONF_SYNC_TEST"})
+		require.NoError(t, err)
+		manager := newSettingManager(storage)
+
+		result, err := manager.SyncFromViper(newSyncTestViper(t), SyncMissing)
+		assert.ErrorIs(t, err, ErrReadOnlyStorage)
+		require.NotNil(t, result)
+		assert.False(t, result.Applied)
+		assert.Len(t, result.Added, 3)
+	})
+
+	t.Run("partial write", func(t *testing.T) {
+		storage := &failingSetStorage{mockStorage: newMockStorage(), failKey: "app.port"}
+		manager := newSettingManager(storage)
+
+		result, err := manager.SyncFromViper(newSyncTestViper(t), SyncMissing)
+		assert.ErrorIs(t, err, ErrStorageOperation)
+		require.NotNil(t, result)
+		assert.True(t, result.Applied)
+		assert.Equal(t, "true", storage.data["app.debug"])
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		storage := newMockStorage()
+		manager := newSettingManager(storage)
+		manager.SetAuthorizer(NewRuleAuthorizer())
+
+		result, err := manager.SyncFromViper(newSyncTestViper(t), SyncMissing)
+		assert.ErrorIs(t, err, ErrPermissionDenied)
+		require.NotNil(t, result)
+		assert.False(t, result.Applied)
+		assert.Len(t, result.Added, 3)
+		assert.Empty(t, storage.data)
+	})
+}
+
+// failingSetStorage 写入 failKey 时返回错误
+type failingSetStorage struct {
+	*mockStorage
+	failKey string
+}
+
+func (fs *failingSetStorage) Set(key, value string) error {
+	if key == fs.failKey {
+		return fmt.Errorf("%w: disk full", ErrStorageOperation)
+	}
+	return fs.mockStorage.Set(key, value)
+}
@@ -0,0 +1,72 @@
+package conf
+
+import (
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
+// ViperMode 控制存储层未命中时如何使用 viper 中的配置
+type ViperMode int
+
+const (
+	// ViperReadOnly 将 viper 作为只读的下层来源，值不会写入存储层（默认）
+	ViperReadOnly ViperMode = iota
+	// ViperWriteThrough 命中 viper 后将值写入存储层，之后配置文件的修改将被存储层覆盖
+	ViperWriteThrough
+	// ViperDisabled 不使用 viper 回退
+	ViperDisabled
+)
+
+// String 返回模式名称
+func (m ViperMode) String() string {
+	switch m {
+	case ViperReadOnly:
+		return "read-only"
+	case ViperWriteThrough:
+		return "write-through"
+	case ViperDisabled:
+		return "disabled"
+	default:
+		return fmt.Sprintf("ViperMode(%d)", int(m))
+	}
+}
+
+// SetViper 设置回退使用的 viper 实例，为 nil 时使用全局 viper
+func (sm *SettingManager) SetViper(v *viper.Viper) {
+	sm.viper = v
+}
+
+// SetViperMode 设置 viper 回退模式
+func (sm *SettingManager) SetViperMode(mode ViperMode) {
+	sm.viperMode = mode
+}
+
+// viperInstance 返回当前使用的 viper 实例
+func (sm *SettingManager) viperInstance() *viper.Viper {
+	if sm.viper != nil {
+		return sm.viper
+	}
+	return viper.GetViper()
+}
+
+// lookupViper 在存储层未命中时从 viper 读取配置
+func (sm *SettingManager) lookupViper(key string) (any, bool, error) {
+	if sm.viperMode == ViperDisabled {
+		return nil, false, nil
+	}
+
+	v := sm.viperInstance()
+	if !v.IsSet(key) {
+		return nil, false, nil
+	}
+
+	result := v.Get(key)
+	if sm.viperMode == ViperWriteThrough {
+		// 找到值后保存到存储层
+		if err := sm.Set(key, result); err != nil {
+			return nil, false, err
+		}
+	}
+	return result, true, nil
+}
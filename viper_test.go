@@ -0,0 +1,64 @@
+package conf
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingManager_ViperFallback(t *testing.T) {
+	t.Run("read-only layer", func(t *testing.T) {
+		storage := newMockStorage()
+		v := viper.New()
+		v.Set("server.port", 8080)
+
+		manager := newSettingManager(storage)
+		manager.SetViper(v)
+
+		got, err := getTyped[int](manager, "server.port")
+		require.NoError(t, err)
+		assert.Equal(t, 8080, *got)
+		assert.NotContains(t, storage.data, "server.port")
+
+		// 配置文件的后续修改立即可见
+		v.Set("server.port", 9090)
+		got, err = getTyped[int](manager, "server.port")
+		require.NoError(t, err)
+		assert.Equal(t, 9090, *got)
+
+		// 存储层的值优先于 viper
+		require.NoError(t, manager.Set("server.port", 7070))
+		got, err = getTyped[int](manager, "server.port")
+		require.NoError(t, err)
+		assert.Equal(t, 7070, *got)
+	})
+
+	t.Run("write-through", func(t *testing.T) {
+		storage := newMockStorage()
+		v := viper.New()
+		v.Set("server.host", "localhost")
+
+		manager := newSettingManager(storage)
+		manager.SetViper(v)
+		manager.SetViperMode(ViperWriteThrough)
+
+		got, err := getTyped[string](manager, "server.host")
+		require.NoError(t, err)
+		assert.Equal(t, "localhost", *got)
+		assert.Equal(t, "localhost", storage.data["server.host"])
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		v := viper.New()
+		v.Set("server.host", "localhost")
+
+		manager := newSettingManager(newMockStorage())
+		manager.SetViper(v)
+		manager.SetViperMode(ViperDisabled)
+
+		_, err := manager.Get("server.host")
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+}
@@ -0,0 +1,43 @@
+package conf
+
+import "sync"
+
+// changeNotifier 管理变更回调，供实现 SettingWatcher 的存储复用
+type changeNotifier struct {
+	mutex  sync.RWMutex
+	nextID int
+	fns    map[int]func(key string)
+}
+
+// Watch 注册变更回调
+func (n *changeNotifier) Watch(fn func(key string)) (cancel func()) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.fns == nil {
+		n.fns = make(map[int]func(key string))
+	}
+	id := n.nextID
+	n.nextID++
+	n.fns[id] = fn
+
+	return func() {
+		n.mutex.Lock()
+		defer n.mutex.Unlock()
+		delete(n.fns, id)
+	}
+}
+
+// notify 通知所有回调键已变化
+func (n *changeNotifier) notify(key string) {
+	n.mutex.RLock()
+	fns := make([]func(key string), 0, len(n.fns))
+	for _, fn := range n.fns {
+		fns = append(fns, fn)
+	}
+	n.mutex.RUnlock()
+
+	for _, fn := range fns {
+		fn(key)
+	}
+}